@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
 	"github.com/faiface/beep/speaker"
 	"github.com/faiface/beep/wav"
 )
@@ -14,17 +15,22 @@ var (
 	isSpeakerInitialized = false
 	sounds               = make(map[Sound]beep.StreamSeeker)
 	loopingSoundCtrl     = make(map[int]*beep.Ctrl)
+	loopingSoundVolume   = make(map[int]*effects.Volume)
 	loopingSoundCount    = 0
 	loaded               = false
 )
 
 type Sound int
 
+// The volume of a ducked sound, as a power of two.
+const duckedVolume = -2
+
 const (
 	ShiftSound Sound = iota
 	SlamSound
 	MergeSound
 	DieSound
+	LevelUpSound
 	BackgroundMusic
 )
 
@@ -37,6 +43,7 @@ func LoadSounds() {
 	sounds[SlamSound] = load("slam")
 	sounds[MergeSound] = load("merge")
 	sounds[ShiftSound] = load("shift")
+	sounds[LevelUpSound] = load("merge-deep")
 	sounds[BackgroundMusic] = load("background-slow")
 	loaded = true
 }
@@ -54,8 +61,12 @@ func PlaySound(sound Sound) {
 func LoopSound(sound Sound) int {
 	speaker.Lock()
 	loopingSoundCount++
-	loopingSoundCtrl[loopingSoundCount] = &beep.Ctrl{
+	loopingSoundVolume[loopingSoundCount] = &effects.Volume{
 		Streamer: beep.Loop(-1, sounds[sound]),
+		Base:     2,
+	}
+	loopingSoundCtrl[loopingSoundCount] = &beep.Ctrl{
+		Streamer: loopingSoundVolume[loopingSoundCount],
 	}
 
 	speaker.Unlock()
@@ -74,6 +85,25 @@ func StopSound(ref int) {
 	loopingSoundCtrl[ref].Paused = true
 	loopingSoundCtrl[ref].Streamer = nil
 	delete(loopingSoundCtrl, ref)
+	delete(loopingSoundVolume, ref)
+	speaker.Unlock()
+}
+
+// DuckSound lowers the volume of a looping sound, or restores it if duck is
+// false.
+//
+// If the given ref does not identify a looping sound, an error is logged.
+func DuckSound(ref int, duck bool) {
+	if _, ok := loopingSoundVolume[ref]; !ok {
+		log.Println("invalid sound ref:", ref)
+		return
+	}
+	speaker.Lock()
+	if duck {
+		loopingSoundVolume[ref].Volume = duckedVolume
+	} else {
+		loopingSoundVolume[ref].Volume = 0
+	}
 	speaker.Unlock()
 }
 
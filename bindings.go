@@ -0,0 +1,368 @@
+package numino
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Action is something the player can do, by pressing a key or gamepad button
+// that is bound to it.
+type Action int
+
+const (
+	ActionShiftLeft Action = iota
+	ActionShiftRight
+	ActionSlam
+	ActionHold
+	ActionPause
+	ActionQuit
+
+	// Menu actions move through menus. They are always bound to the same keys
+	// and buttons.
+	ActionMenuUp
+	ActionMenuDown
+	ActionMenuSelect
+	ActionMenuBack
+)
+
+// Actions lists every action that can be bound, in the order they are shown to
+// the player.
+var Actions = []Action{
+	ActionShiftLeft,
+	ActionShiftRight,
+	ActionSlam,
+	ActionHold,
+	ActionPause,
+	ActionQuit,
+}
+
+// actionNames are the names of actions in a bindings file.
+var actionNames = map[Action]string{
+	ActionShiftLeft:  "shiftLeft",
+	ActionShiftRight: "shiftRight",
+	ActionSlam:       "slam",
+	ActionHold:       "hold",
+	ActionPause:      "pause",
+	ActionQuit:       "quit",
+}
+
+// Desc returns a description of this action for the player.
+func (a Action) Desc() string {
+	switch a {
+	case ActionShiftLeft:
+		return "shift left"
+	case ActionShiftRight:
+		return "shift right"
+	case ActionSlam:
+		return "slam blocks to bottom of screen"
+	case ActionHold:
+		return "hold blocks, or swap with held blocks"
+	case ActionPause:
+		return "pause"
+	case ActionQuit:
+		return "exit to main menu"
+	}
+	return "unknown action"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Action) MarshalText() ([]byte, error) {
+	name, ok := actionNames[a]
+	if !ok {
+		return nil, fmt.Errorf("unknown action: %d", int(a))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Action) UnmarshalText(text []byte) error {
+	for action, name := range actionNames {
+		if name == string(text) {
+			*a = action
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action: %q", text)
+}
+
+// Key is a key on the keyboard, saved by its name in a bindings file.
+type Key pixelgl.Button
+
+// String returns the name of this key.
+func (k Key) String() string {
+	return pixelgl.Button(k).String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k Key) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *Key) UnmarshalText(text []byte) error {
+	for b := pixelgl.KeySpace; b <= pixelgl.KeyLast; b++ {
+		if name := b.String(); name != "Invalid" && strings.EqualFold(name, string(text)) {
+			*k = Key(b)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key: %q", text)
+}
+
+// GamepadButton is a button on a gamepad, saved by its name in a bindings file.
+type GamepadButton pixelgl.GamepadButton
+
+// gamepadButtonNames are the names of gamepad buttons in a bindings file.
+var gamepadButtonNames = map[GamepadButton]string{
+	GamepadButton(pixelgl.ButtonA):           "A",
+	GamepadButton(pixelgl.ButtonB):           "B",
+	GamepadButton(pixelgl.ButtonX):           "X",
+	GamepadButton(pixelgl.ButtonY):           "Y",
+	GamepadButton(pixelgl.ButtonLeftBumper):  "LeftBumper",
+	GamepadButton(pixelgl.ButtonRightBumper): "RightBumper",
+	GamepadButton(pixelgl.ButtonBack):        "Back",
+	GamepadButton(pixelgl.ButtonStart):       "Start",
+	GamepadButton(pixelgl.ButtonGuide):       "Guide",
+	GamepadButton(pixelgl.ButtonLeftThumb):   "LeftThumb",
+	GamepadButton(pixelgl.ButtonRightThumb):  "RightThumb",
+	GamepadButton(pixelgl.ButtonDpadUp):      "DpadUp",
+	GamepadButton(pixelgl.ButtonDpadRight):   "DpadRight",
+	GamepadButton(pixelgl.ButtonDpadDown):    "DpadDown",
+	GamepadButton(pixelgl.ButtonDpadLeft):    "DpadLeft",
+}
+
+// String returns the name of this button.
+func (b GamepadButton) String() string {
+	if name, ok := gamepadButtonNames[b]; ok {
+		return name
+	}
+	return "Invalid"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b GamepadButton) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *GamepadButton) UnmarshalText(text []byte) error {
+	for button, name := range gamepadButtonNames {
+		if strings.EqualFold(name, string(text)) {
+			*b = button
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gamepad button: %q", text)
+}
+
+// reservedKey always pauses the game and backs out of menus, so it cannot be
+// bound to an action.
+const reservedKey = Key(pixelgl.KeyEscape)
+
+// Bindings are the keys and gamepad buttons bound to each action.
+type Bindings struct {
+	Keys    map[Action][]Key `json:"keys"`
+	Gamepad GamepadBindings  `json:"gamepad"`
+	// AutoShift is how shifting repeats while a shift key or button is held.
+	AutoShift AutoShift `json:"autoShift"`
+}
+
+// GamepadBindings are the gamepad buttons bound to each action.
+//
+// The left stick always shifts the falling blocks, like the d-pad does in
+// menus.
+type GamepadBindings struct {
+	Buttons map[Action][]GamepadButton `json:"buttons"`
+	// Deadzone is how far a stick must be pushed from its center, from 0 to 1,
+	// before it counts as pushed.
+	Deadzone float64 `json:"deadzone"`
+}
+
+// DefaultBindings returns the bindings of a new player.
+func DefaultBindings() *Bindings {
+	return &Bindings{
+		Keys: map[Action][]Key{
+			ActionShiftLeft:  {Key(pixelgl.KeyA)},
+			ActionShiftRight: {Key(pixelgl.KeyD)},
+			ActionSlam:       {Key(pixelgl.KeyS)},
+			ActionHold:       {Key(pixelgl.KeyW)},
+			ActionPause:      {Key(pixelgl.KeyP)},
+			ActionQuit:       {Key(pixelgl.KeyQ)},
+		},
+		Gamepad: GamepadBindings{
+			Buttons: map[Action][]GamepadButton{
+				ActionShiftLeft:  {GamepadButton(pixelgl.ButtonDpadLeft)},
+				ActionShiftRight: {GamepadButton(pixelgl.ButtonDpadRight)},
+				ActionSlam:       {GamepadButton(pixelgl.ButtonA)},
+				ActionHold:       {GamepadButton(pixelgl.ButtonY)},
+				ActionPause:      {GamepadButton(pixelgl.ButtonStart)},
+				ActionQuit:       {GamepadButton(pixelgl.ButtonBack)},
+			},
+			Deadzone: 0.5,
+		},
+		AutoShift: DefaultAutoShift(),
+	}
+}
+
+// KeyNames returns the names of the keys bound to action, separated by commas.
+func (b *Bindings) KeyNames(action Action) string {
+	names := make([]string, len(b.Keys[action]))
+	for i, key := range b.Keys[action] {
+		names[i] = key.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// ButtonNames returns the names of the gamepad buttons bound to action,
+// separated by commas.
+func (b *Bindings) ButtonNames(action Action) string {
+	buttons := b.Gamepad.Buttons[action]
+	names := make([]string, len(buttons))
+	for i, button := range buttons {
+		names[i] = button.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Conflict returns the action other than action that key is bound to, and
+// true, or false if key is free to bind to action.
+func (b *Bindings) Conflict(action Action, key Key) (Action, bool) {
+	for _, other := range Actions {
+		if other == action {
+			continue
+		}
+		for _, k := range b.Keys[other] {
+			if k == key {
+				return other, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Bind replaces the keys bound to action with key.
+//
+// Returns an error, and leaves these bindings unchanged, if key is reserved
+// or already bound to a different action.
+func (b *Bindings) Bind(action Action, key Key) error {
+	if key == reservedKey {
+		return fmt.Errorf("%v is reserved", key)
+	}
+	if other, ok := b.Conflict(action, key); ok {
+		return fmt.Errorf("%v is already bound to %s", key, other.Desc())
+	}
+	b.Keys[action] = []Key{key}
+	return nil
+}
+
+// Validate returns an error if an action has no keys, if a key is reserved or
+// bound to more than one action, if a gamepad button is bound to more than one
+// action, or if the gamepad deadzone or auto shift is out of range.
+func (b *Bindings) Validate() error {
+	for _, action := range Actions {
+		keys := b.Keys[action]
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys bound to %s", actionNames[action])
+		}
+		for _, key := range keys {
+			if key == reservedKey {
+				return fmt.Errorf("%v is reserved", key)
+			}
+			if other, ok := b.Conflict(action, key); ok {
+				return fmt.Errorf("%v is bound to both %s and %s", key, actionNames[action], actionNames[other])
+			}
+		}
+	}
+
+	bound := make(map[GamepadButton]Action)
+	for _, action := range Actions {
+		for _, button := range b.Gamepad.Buttons[action] {
+			if other, ok := bound[button]; ok && other != action {
+				return fmt.Errorf("gamepad button %v is bound to both %s and %s",
+					button, actionNames[other], actionNames[action])
+			}
+			bound[button] = action
+		}
+	}
+	if b.Gamepad.Deadzone < 0 || b.Gamepad.Deadzone >= 1 {
+		return fmt.Errorf("gamepad deadzone must be in [0, 1), got %v", b.Gamepad.Deadzone)
+	}
+	if b.AutoShift.Delay < 0 || b.AutoShift.Rate < 1 {
+		return fmt.Errorf("auto shift delay must not be negative and rate must be at least 1, got %+v", b.AutoShift)
+	}
+	return nil
+}
+
+// WriteBindings writes the given bindings to w.
+func WriteBindings(w io.Writer, b *Bindings) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// ReadBindings reads bindings from r.
+//
+// Actions missing from the file keep their default keys. An error is returned
+// if the bindings are invalid.
+func ReadBindings(r io.Reader) (*Bindings, error) {
+	b := DefaultBindings()
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(b); err != nil {
+		return nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// BindingsPath returns the path of the file where bindings are saved.
+func BindingsPath() (string, error) {
+	return configPath("bindings.json")
+}
+
+// LoadBindings reads the bindings at BindingsPath.
+//
+// If the file is missing, the default bindings are returned. If the file
+// cannot be read or is invalid, the error is logged and the default bindings
+// are returned.
+func LoadBindings() *Bindings {
+	path, err := BindingsPath()
+	if err != nil {
+		log.Println("failed to find bindings:", err)
+		return DefaultBindings()
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return DefaultBindings()
+	}
+	if err != nil {
+		log.Println("failed to load bindings:", err)
+		return DefaultBindings()
+	}
+	defer f.Close()
+	b, err := ReadBindings(f)
+	if err != nil {
+		log.Printf("failed to load bindings from %s: %v", path, err)
+		return DefaultBindings()
+	}
+	return b
+}
+
+// StoreBindings writes the given bindings to BindingsPath.
+func StoreBindings(b *Bindings) error {
+	path, err := BindingsPath()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteBindings(w, b)
+	})
+}
@@ -0,0 +1,168 @@
+package numino
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+func TestReadBindingsKeepsDefaults(t *testing.T) {
+	b, err := ReadBindings(strings.NewReader(`{"keys": {"slam": ["J"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only slam is rebound. Every other action keeps its default keys, and
+	// the gamepad and auto shift are untouched.
+	want := DefaultBindings()
+	want.Keys[ActionSlam] = []Key{Key(pixelgl.KeyJ)}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("ReadBindings() = %+v, want %+v", b, want)
+	}
+}
+
+func TestReadBindingsRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{
+			name: "key bound to two actions",
+			json: `{"keys": {"slam": ["A"]}}`,
+		},
+		{
+			name: "reserved key",
+			json: `{"keys": {"pause": ["Escape"]}}`,
+		},
+		{
+			name: "no keys",
+			json: `{"keys": {"hold": []}}`,
+		},
+		{
+			name: "gamepad button bound to two actions",
+			json: `{"gamepad": {"buttons": {"hold": ["A"]}}}`,
+		},
+		{
+			name: "deadzone out of range",
+			json: `{"gamepad": {"deadzone": 1}}`,
+		},
+		{
+			name: "auto shift rate out of range",
+			json: `{"autoShift": {"rate": 0}}`,
+		},
+		{
+			name: "unknown key",
+			json: `{"keys": {"slam": ["NotAKey"]}}`,
+		},
+		{
+			name: "unknown action",
+			json: `{"keys": {"jump": ["J"]}}`,
+		},
+		{
+			name: "unknown field",
+			json: `{"mouse": {}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if b, err := ReadBindings(strings.NewReader(test.json)); err == nil {
+				t.Errorf("ReadBindings() = %+v, want error", b)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  Action
+		key     Key
+		wantErr bool
+	}{
+		{
+			name:   "free key",
+			action: ActionSlam,
+			key:    Key(pixelgl.KeyJ),
+		},
+		{
+			name:   "key already bound to the same action",
+			action: ActionSlam,
+			key:    Key(pixelgl.KeyS),
+		},
+		{
+			name:    "key bound to another action",
+			action:  ActionSlam,
+			key:     Key(pixelgl.KeyA),
+			wantErr: true,
+		},
+		{
+			name:    "reserved key",
+			action:  ActionPause,
+			key:     reservedKey,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := DefaultBindings()
+			err := b.Bind(test.action, test.key)
+			want := DefaultBindings()
+			if test.wantErr {
+				if err == nil {
+					t.Error("Bind() succeeded, want error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Bind() failed: %v", err)
+				}
+				want.Keys[test.action] = []Key{test.key}
+			}
+			if !reflect.DeepEqual(b, want) {
+				t.Errorf("bindings after Bind() = %+v, want %+v", b, want)
+			}
+		})
+	}
+}
+
+func TestBindingsRoundTrip(t *testing.T) {
+	b := DefaultBindings()
+	b.Keys[ActionShiftLeft] = []Key{Key(pixelgl.KeyLeft), Key(pixelgl.KeyA)}
+	b.Keys[ActionSlam] = []Key{Key(pixelgl.KeySpace)}
+	b.Gamepad.Buttons[ActionHold] = []GamepadButton{GamepadButton(pixelgl.ButtonRightBumper)}
+	b.Gamepad.Deadzone = 0.25
+	b.AutoShift = AutoShift{Delay: 8, Rate: 3}
+
+	var buf bytes.Buffer
+	if err := WriteBindings(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	// Keys and buttons are saved by name, so the file can be edited by hand.
+	for _, name := range []string{`"Left"`, `"Space"`, `"RightBumper"`, `"shiftLeft"`} {
+		if !strings.Contains(buf.String(), name) {
+			t.Errorf("bindings file does not contain %s:\n%s", name, buf.String())
+		}
+	}
+
+	got, err := ReadBindings(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("ReadBindings(WriteBindings()) = %+v, want %+v", got, b)
+	}
+}
+
+func TestKeyUnmarshalTextIgnoresCase(t *testing.T) {
+	var k Key
+	if err := k.UnmarshalText([]byte("space")); err != nil {
+		t.Fatal(err)
+	}
+	if k != Key(pixelgl.KeySpace) {
+		t.Errorf("UnmarshalText(%q) = %v, want %v", "space", k, Key(pixelgl.KeySpace))
+	}
+}
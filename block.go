@@ -2,7 +2,6 @@ package numino
 
 import (
 	"math/rand"
-	"time"
 )
 
 // Block represents an object that occupies a space on the game grid.
@@ -25,16 +24,42 @@ const (
 // FallingBlocks manages the cells that currently under player-control.
 type FallingBlocks struct {
 	// The cells under player control.
-	blocks  []Block
-	counter counter
-	random  *rand.Rand
+	blocks []Block
+	// The waves that come into play after the current one, in order. Some
+	// waves may be empty.
+	upcoming [][]Block
+	// The wave in the hold slot, if any, and whether the current wave came
+	// into play by holding.
+	held     []Block
+	holdUsed bool
+	counter  counter
+	rules    Rules
+	// The level that new waves are generated for.
+	level  Level
+	source *countingSource
+	random *rand.Rand
 }
 
 // NewFallingBlocks returns a pointer to a new FallingBlocks.
-func NewFallingBlocks(ticksPerStep float64) *FallingBlocks {
+//
+// The given seed determines the waves of blocks generated by NextWave. Two
+// FallingBlocks with the same rules and seed generate the same waves.
+func NewFallingBlocks(rules Rules, seed int64) *FallingBlocks {
+	blocks := newFallingBlocks(rules, seed)
+	blocks.fillUpcoming()
+	return blocks
+}
+
+// newFallingBlocks returns a FallingBlocks with no upcoming waves.
+func newFallingBlocks(rules Rules, seed int64) *FallingBlocks {
+	source := newCountingSource(seed)
+	level := rules.Level(1)
 	return &FallingBlocks{
-		counter: counter{Ticks: ticksPerStep},
-		random:  rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
+		counter: counter{Ticks: level.TicksPerStep},
+		rules:   rules,
+		level:   level,
+		source:  source,
+		random:  rand.New(source),
 	}
 }
 
@@ -106,30 +131,172 @@ func (blocks *FallingBlocks) Remove(row int, col int) {
 	}
 }
 
-// Random generates a new set of cells in the first row.
-func (blocks *FallingBlocks) Random(count int) {
-	for i := 0; i < count; i++ {
-		if (blocks.random.Int() % 5) > 3 {
-			value := blocks.random.Int()%10 - 3
-			if value == 0 {
-				value = 1
-			}
-			blocks.Add(0, i, value)
+// NextWave adds the next wave of blocks to the first row at the given ticks.
+// The wave waits a full step before it falls.
+//
+// The next wave may be empty, in which case nothing is added.
+func (blocks *FallingBlocks) NextWave(ticks float64) {
+	blocks.holdUsed = false
+	blocks.counter.Reset(ticks)
+	if len(blocks.upcoming) == 0 {
+		blocks.upcoming = append(blocks.upcoming, blocks.randomWave())
+	}
+	wave := blocks.upcoming[0]
+	blocks.upcoming = blocks.upcoming[1:]
+	for _, block := range wave {
+		blocks.Add(block.Row, block.Col, block.Value)
+	}
+	blocks.fillUpcoming()
+}
+
+// Hold puts the falling blocks in the hold slot, back in the first row, and
+// brings the previously held wave into play. If no wave was held, the next
+// non-empty wave comes into play instead.
+//
+// Holding is allowed once per wave: a wave that came into play by holding
+// cannot be held until it lands. Like a new wave, the wave that comes into
+// play at the given ticks waits a full step before it falls. Returns true iff
+// the blocks were held.
+func (blocks *FallingBlocks) Hold(ticks float64) bool {
+	if !blocks.CanHold() {
+		return false
+	}
+	wave := blocks.blocks
+	for i := range wave {
+		wave[i].Row = 0
+	}
+	blocks.blocks = nil
+	if blocks.held != nil {
+		blocks.blocks = blocks.held
+	} else {
+		for len(blocks.blocks) == 0 {
+			blocks.NextWave(ticks)
+		}
+	}
+	blocks.counter.Reset(ticks)
+	blocks.held = wave
+	blocks.holdUsed = true
+	return true
+}
+
+// CanHold returns true iff Hold would hold the falling blocks.
+func (blocks FallingBlocks) CanHold() bool {
+	return !blocks.holdUsed && len(blocks.blocks) > 0
+}
+
+// Held returns a copy of the wave in the hold slot, or nil if no wave is
+// held.
+func (blocks FallingBlocks) Held() []Block {
+	return append([]Block(nil), blocks.held...)
+}
+
+// Upcoming returns copies of the waves that come into play after the current
+// one, in order. Empty waves are left out, so there are as many waves as the
+// rules' preview length.
+func (blocks FallingBlocks) Upcoming() [][]Block {
+	var waves [][]Block
+	for _, wave := range blocks.upcoming {
+		if len(wave) > 0 {
+			waves = append(waves, append([]Block(nil), wave...))
+		}
+	}
+	return waves
+}
+
+// fillUpcoming generates waves until there are as many non-empty upcoming
+// waves as the rules' preview length.
+func (blocks *FallingBlocks) fillUpcoming() {
+	for len(blocks.Upcoming()) < blocks.rules.PreviewWaves {
+		blocks.upcoming = append(blocks.upcoming, blocks.randomWave())
+	}
+}
+
+// randomWave generates a new wave of blocks in the first row.
+//
+// A block spawns in each column with the level's spawn chance, and its value is
+// chosen evenly from the level's value range, excluding zero.
+func (blocks *FallingBlocks) randomWave() []Block {
+	var wave []Block
+	for i := 0; i < blocks.rules.Cols; i++ {
+		if blocks.random.Float64() < blocks.level.SpawnChance {
+			wave = append(wave, Block{Row: 0, Col: i, Value: blocks.randomValue()})
 		}
 	}
+	return wave
+}
+
+func (blocks *FallingBlocks) randomValue() int {
+	min, max := blocks.level.MinValue, blocks.level.MaxValue
+	n := max - min + 1
+	if min <= 0 && 0 <= max {
+		n--
+	}
+	value := min + blocks.random.Intn(n)
+	if min <= 0 && value >= 0 {
+		// Skip over zero.
+		value++
+	}
+	return value
 }
 
-func (blocks *FallingBlocks) Speedup() {
-	blocks.counter.Ticks *= .9
+// SetLevel makes the blocks fall at the speed of the given level.
+//
+// Waves generated from now on use the level's spawn chance and values. Waves
+// that are already upcoming keep those of the level they were generated for.
+func (blocks *FallingBlocks) SetLevel(level Level) {
+	blocks.level = level
+	blocks.counter.Ticks = level.TicksPerStep
 }
 
 func (blocks *FallingBlocks) Slam(game *GameState) {
 	for i := range blocks.blocks {
-		for blocks.blocks[i].Row < game.RowCount() &&
-			game.IsEmpty(blocks.blocks[i].Row, blocks.blocks[i].Col) {
-			blocks.blocks[i].Row++
+		blocks.blocks[i].Row = slamRow(blocks.blocks[i], game)
+	}
+}
+
+// slamRow returns the row that block moves to when it is slammed: the first
+// row at or below it that is not empty, or the row below the grid.
+func slamRow(block Block, game *GameState) int {
+	row := block.Row
+	for row < game.RowCount() && game.IsEmpty(row, block.Col) {
+		row++
+	}
+	return row
+}
+
+// Landing describes where a falling block would land.
+type Landing struct {
+	Type LandingType
+	// Block is the block that would be added to the grid, at the cell it
+	// would be added to.
+	Block Block
+	// Value is the value the cell would hold after the block is added.
+	Value int
+	// Dies is true if the cell would hold a dead block.
+	Dies bool
+}
+
+// PredictSlam returns where each of these blocks would land if they were
+// slammed, in the same order as Blocks.
+//
+// Neither these blocks nor game are changed.
+func (blocks FallingBlocks) PredictSlam(game *GameState) []Landing {
+	landings := make([]Landing, 0, len(blocks.blocks))
+	for _, block := range blocks.blocks {
+		block.Row = slamRow(block, game)
+		landingType, row, col := blocks.DescribeLanding(block, game)
+		landing := Landing{
+			Type:  landingType,
+			Block: Block{Row: row, Col: col, Value: block.Value},
+			Value: block.Value,
+		}
+		if row >= 0 {
+			landing.Value += game.ValueAt(row, col)
 		}
+		landing.Dies = !game.IsLiveValue(landing.Value)
+		landings = append(landings, landing)
 	}
+	return landings
 }
 
 type direction int
@@ -195,3 +362,37 @@ func (blocks *FallingBlocks) neighbor(dir direction, block Block) *Block {
 	}
 	return nil
 }
+
+// countingSource is a rand.Source that counts the values it has generated.
+//
+// The state of a countingSource can be saved as its seed and number of draws,
+// and restored by drawing the same number of values from a new source.
+type countingSource struct {
+	source rand.Source
+	seed   int64
+	draws  int64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{source: rand.NewSource(seed), seed: seed}
+}
+
+// Int63 implements rand.Source.
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.source.Int63()
+}
+
+// Seed implements rand.Source.
+func (s *countingSource) Seed(seed int64) {
+	s.source.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// Skip draws n values from this source and discards them.
+func (s *countingSource) Skip(n int64) {
+	for i := int64(0); i < n; i++ {
+		s.Int63()
+	}
+}
@@ -0,0 +1,84 @@
+package numino
+
+import "testing"
+
+// spawn brings the next non-empty wave into play at the given ticks.
+func spawn(blocks *FallingBlocks, ticks float64) {
+	for blocks.Length() == 0 {
+		blocks.NextWave(ticks)
+	}
+}
+
+func TestFallingBlocksWaitAFullStep(t *testing.T) {
+	rules := DefaultRules()
+	step := rules.Level(1).TicksPerStep
+
+	tests := []struct {
+		name string
+		// setup runs at tick 0, after the first wave came into play.
+		setup func(blocks *FallingBlocks)
+		// bringIntoPlay brings a new wave into play at the given ticks, just
+		// before the first wave would have fallen.
+		bringIntoPlay func(t *testing.T, blocks *FallingBlocks, ticks float64)
+	}{
+		{
+			name: "new wave",
+			bringIntoPlay: func(t *testing.T, blocks *FallingBlocks, ticks float64) {
+				blocks.Clear()
+				spawn(blocks, ticks)
+			},
+		},
+		{
+			name: "hold with no held wave",
+			bringIntoPlay: func(t *testing.T, blocks *FallingBlocks, ticks float64) {
+				if !blocks.Hold(ticks) {
+					t.Fatal("Hold() = false, want true")
+				}
+			},
+		},
+		{
+			name: "swap with held wave",
+			setup: func(blocks *FallingBlocks) {
+				blocks.Hold(0)
+				blocks.Clear()
+				spawn(blocks, 0)
+			},
+			bringIntoPlay: func(t *testing.T, blocks *FallingBlocks, ticks float64) {
+				if !blocks.Hold(ticks) {
+					t.Fatal("Hold() = false, want true")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := NewFallingBlocks(rules, 1)
+			spawn(blocks, 0)
+			if test.setup != nil {
+				test.setup(blocks)
+			}
+			ticks := 1.0
+			for ; ticks <= step; ticks++ {
+				blocks.Update(ticks, nil)
+			}
+			test.bringIntoPlay(t, blocks, step)
+
+			// The wave stays in the first row for a full step, then falls.
+			for ; ticks <= 2*step; ticks++ {
+				blocks.Update(ticks, nil)
+				for _, block := range blocks.Blocks() {
+					if block.Row != 0 {
+						t.Fatalf("tick %v: block fell to row %d, want row 0", ticks, block.Row)
+					}
+				}
+			}
+			blocks.Update(ticks, nil)
+			for _, block := range blocks.Blocks() {
+				if block.Row != 1 {
+					t.Fatalf("tick %v: block is in row %d, want row 1", ticks, block.Row)
+				}
+			}
+		})
+	}
+}
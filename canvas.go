@@ -0,0 +1,275 @@
+package numino
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+)
+
+// Canvas is a surface that a game can be drawn on.
+//
+// Positions are in pixels, as computed by a Grid, with the origin at the
+// bottom left corner.
+type Canvas interface {
+	// Rect fills the rectangle whose bottom left corner is at x, y.
+	Rect(x, y, w, h float64, c color.RGBA)
+	// Text draws msg with the start of its baseline at x, y.
+	Text(x, y float64, msg string, c color.RGBA)
+	// TextWidth returns the width of msg when it is drawn with Text.
+	TextWidth(msg string) float64
+}
+
+const (
+	// The number of steps a slam trail is shown for.
+	slamTrailSteps = 30
+	// The number of steps cleared cells are highlighted for.
+	clearedSteps = 20
+	// The number of steps a new level is announced for.
+	levelUpSteps = 90
+)
+
+// Effects are the short-lived effects drawn on the board of a game: the
+// trail left behind by slammed blocks, the cells that were cleared, and the
+// announcement of a new level.
+type Effects struct {
+	// The blocks covered by the slam trail, and the steps left to show it.
+	slamTrail      []Block
+	slamTrailSteps int
+	// The cells that were cleared, and the steps left to highlight them.
+	cleared      []Block
+	clearedSteps int
+	// The level that was reached, and the steps left to announce it.
+	level        int
+	levelUpSteps int
+}
+
+// Update is called for every step of the game. It starts new effects for the
+// events in the step, and otherwise fades out the current effects.
+func (fx *Effects) Update(result StepResult) {
+	if fx.slamTrailSteps > 0 {
+		fx.slamTrailSteps--
+	}
+	if fx.clearedSteps > 0 {
+		fx.clearedSteps--
+	}
+	if fx.levelUpSteps > 0 {
+		fx.levelUpSteps--
+	}
+
+	if result.Slammed {
+		fx.slamTrail = fx.slamTrail[:0]
+		for i := range result.SlamStart {
+			col := result.SlamStart[i].Col
+			for row := result.SlamStart[i].Row; row < result.SlamEnd[i].Row; row++ {
+				fx.slamTrail = append(fx.slamTrail, Block{Row: row, Col: col})
+			}
+		}
+		fx.slamTrailSteps = slamTrailSteps
+	}
+	if len(result.Chain) > 0 {
+		fx.cleared = fx.cleared[:0]
+		for _, step := range result.Chain {
+			fx.cleared = append(fx.cleared, step.Cleared...)
+		}
+		fx.clearedSteps = clearedSteps
+	}
+	if result.LevelUp {
+		fx.level = result.Level
+		fx.levelUpSteps = levelUpSteps
+	}
+}
+
+// Draw draws the effects that are still shown beneath the blocks.
+func (fx *Effects) Draw(canvas Canvas, grid *Grid) {
+	if fx.slamTrailSteps > 0 {
+		drawCells(fx.slamTrail, grid, ColorSlamTrail, canvas)
+	}
+	if fx.clearedSteps > 0 {
+		drawCells(fx.cleared, grid, ColorCleared, canvas)
+	}
+}
+
+// drawLevelUp announces a new level in a band across the middle of the board,
+// on top of the blocks.
+func (fx *Effects) drawLevelUp(canvas Canvas, grid *Grid) {
+	if fx.levelUpSteps == 0 {
+		return
+	}
+	row := grid.Rows / 2
+	canvas.Rect(0, grid.RowToPixel(row), grid.PixelWidth(), grid.SquareSize, ColorLevelUp)
+	msg := fmt.Sprintf("Level %d!", fx.level)
+	canvas.Text((grid.PixelWidth()-canvas.TextWidth(msg))/2, grid.RowToCell(row), msg, ColorText)
+}
+
+// drawCells fills the cells of the given blocks.
+func drawCells(blocks []Block, grid *Grid, color color.RGBA, canvas Canvas) {
+	for _, block := range blocks {
+		canvas.Rect(grid.ColumnToPixel(block.Col), grid.RowToPixel(block.Row),
+			grid.SquareSize, grid.SquareSize, color)
+	}
+}
+
+// DrawGame draws the board of the game run by engine, with its score and level
+// on top. fx may be nil.
+func DrawGame(canvas Canvas, grid *Grid, engine *Engine, fx *Effects) {
+	DrawBoard(canvas, grid, engine, fx)
+	drawScore(engine.Score(), grid, canvas)
+	drawLevel(engine.Level(), grid, canvas)
+}
+
+// DrawBoard draws the effects, grid and falling blocks of the game run by
+// engine, and a ghost of each falling block where it would land if slammed.
+// fx may be nil.
+func DrawBoard(canvas Canvas, grid *Grid, engine *Engine, fx *Effects) {
+	if fx != nil {
+		fx.Draw(canvas, grid)
+	}
+	drawGrid(engine.Game(), grid, canvas, engine.PredictSlam())
+	for _, block := range engine.FallingBlocks() {
+		drawBlock(block, grid, ColorFallingBlock, canvas)
+	}
+	if fx != nil {
+		fx.drawLevelUp(canvas, grid)
+	}
+}
+
+func drawBlock(block Block, grid *Grid, color color.RGBA, canvas Canvas) {
+	canvas.Rect(grid.ColumnToPixel(block.Col), grid.RowToPixel(block.Row),
+		grid.SquareSize, grid.SquareSize, color)
+	canvas.Text(grid.ColumnToCell(block.Col), grid.RowToCell(block.Row),
+		strconv.Itoa(block.Value), ColorText)
+}
+
+// drawGrid draws the blocks on the grid, and a ghost for each of the given
+// landings.
+func drawGrid(game *GameState, grid *Grid, canvas Canvas, ghosts []Landing) {
+	ghostAt := make(map[Block]Landing)
+	for _, ghost := range ghosts {
+		ghostAt[Block{Row: ghost.Block.Row, Col: ghost.Block.Col}] = ghost
+	}
+
+	for row := 0; row < game.RowCount(); row++ {
+		for col := 0; col < game.ColCount(); col++ {
+			if ghost, ok := ghostAt[Block{Row: row, Col: col}]; ok {
+				drawGhost(game, ghost, grid, canvas)
+			} else if !game.IsEmpty(row, col) {
+				color := ColorLiveBlock
+				if game.IsDead(row, col) {
+					color = ColorDeadBlock
+				}
+
+				block := Block{
+					Col:   col,
+					Row:   row,
+					Value: game.ValueAt(row, col),
+				}
+				drawBlock(block, grid, color, canvas)
+			}
+		}
+	}
+}
+
+// drawGhost draws a translucent block where a falling block would land,
+// labelled with the value of the cell after landing. If the cell already
+// holds a block, and there is room, the label shows its value before and
+// after.
+func drawGhost(game *GameState, ghost Landing, grid *Grid, canvas Canvas) {
+	row, col := ghost.Block.Row, ghost.Block.Col
+	x, y := grid.ColumnToPixel(col), grid.RowToPixel(row)
+
+	label := strconv.Itoa(ghost.Value)
+	if !game.IsEmpty(row, col) {
+		canvas.Rect(x, y, grid.SquareSize, grid.SquareSize, ColorLiveBlock)
+		// The font has no arrows. Where the label is too wide for the square,
+		// such as on a terminal, only the value after landing is shown.
+		if both := strconv.Itoa(game.ValueAt(row, col)) + ">" + label; canvas.TextWidth(both) <= grid.SquareSize {
+			label = both
+		}
+	}
+	color := ColorGhost
+	if ghost.Dies {
+		color = ColorGhostDead
+	}
+	canvas.Rect(x, y, grid.SquareSize, grid.SquareSize, color)
+	// The label can be wider than a value, so center it.
+	canvas.Text(x+(grid.SquareSize-canvas.TextWidth(label))/2, grid.RowToCell(row), label, ColorText)
+}
+
+func drawScore(score int, grid *Grid, canvas Canvas) {
+	canvas.Text(grid.ColumnToCell(grid.Cols-2), grid.RowToCell(0),
+		fmt.Sprintf("Score: %v", score), ColorText)
+}
+
+// drawLevel draws the level on the line below the score.
+func drawLevel(level int, grid *Grid, canvas Canvas) {
+	canvas.Text(grid.ColumnToCell(grid.Cols-2), grid.RowToCell(0)-panelLineHeight,
+		fmt.Sprintf("Level %v", level), ColorText)
+}
+
+const (
+	// The space around the contents of the side panel, in pixels.
+	panelMargin = 10
+	// The height of a line of text in the side panel, in pixels.
+	panelLineHeight = 16
+	// The size of the cells in the side panel, relative to the grid's cells.
+	panelCellScale = 0.4
+	// The most upcoming waves shown in the side panel.
+	panelWaves = 3
+)
+
+// SidePanelWidth returns the width of the panel drawn to the right of a board
+// on grid by DrawSidePanel.
+func SidePanelWidth(grid *Grid) float64 {
+	return float64(grid.Cols)*panelCellSize(grid) + 2*panelMargin
+}
+
+// DrawSidePanel draws a panel to the right of the board of the game run by
+// engine, showing the upcoming waves and the held wave.
+func DrawSidePanel(canvas Canvas, grid *Grid, engine *Engine) {
+	drawSidePanel(canvas, grid, engine.UpcomingWaves(), engine.HeldWave(), engine.CanHold())
+}
+
+func drawSidePanel(canvas Canvas, grid *Grid, upcoming [][]Block, held []Block, canHold bool) {
+	x := grid.PixelWidth()
+	canvas.Rect(x, 0, SidePanelWidth(grid), grid.PixelHeight(), ColorPanel)
+	if len(upcoming) > panelWaves {
+		upcoming = upcoming[:panelWaves]
+	}
+	y := drawPanelWaves(canvas, grid, grid.PixelHeight()-panelMargin, "Next", upcoming, ColorFallingBlock)
+
+	// The held wave is grayed out while it cannot be swapped in.
+	color := ColorFallingBlock
+	if !canHold {
+		color = ColorHoldUsed
+	}
+	drawPanelWaves(canvas, grid, y-panelMargin, "Hold", [][]Block{held}, color)
+}
+
+// drawPanelWaves draws a label followed by a row of small cells for each of
+// the given waves, starting at the top y of the side panel.
+//
+// Returns the y position below the last wave.
+func drawPanelWaves(canvas Canvas, grid *Grid, y float64, label string, waves [][]Block, color color.RGBA) float64 {
+	x := grid.PixelWidth() + panelMargin
+	size := panelCellSize(grid)
+	y -= panelLineHeight
+	canvas.Text(x, y+4, label, ColorText)
+
+	for _, wave := range waves {
+		y -= size + panelMargin/2
+		for col := 0; col < grid.Cols; col++ {
+			canvas.Rect(x+float64(col)*size+1, y+1, size-2, size-2, ColorPanelSlot)
+		}
+		for _, block := range wave {
+			cellX := x + float64(block.Col)*size
+			canvas.Rect(cellX+1, y+1, size-2, size-2, color)
+			value := strconv.Itoa(block.Value)
+			canvas.Text(cellX+(size-canvas.TextWidth(value))/2, y+size/2-4, value, ColorText)
+		}
+	}
+	return y
+}
+
+func panelCellSize(grid *Grid) float64 {
+	return grid.SquareSize * panelCellScale
+}
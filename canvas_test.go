@@ -0,0 +1,251 @@
+package numino
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden files in testdata")
+
+// testGrid is the grid that golden images are drawn on.
+var testGrid = &Grid{Rows: 4, Cols: 3, SquareSize: 50}
+
+func TestDrawGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		// panel is true if the canvas has room for the side panel.
+		panel bool
+		draw  func(canvas Canvas)
+	}{
+		{
+			name: "empty",
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t), testGrid, canvas, nil)
+			},
+		},
+		{
+			name: "mixed",
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t,
+					Block{Row: 3, Col: 0, Value: 12},
+					Block{Row: 3, Col: 1, Value: 4},
+					Block{Row: 2, Col: 0, Value: 10},
+					Block{Row: 3, Col: 2, Value: 11},
+				), testGrid, canvas, nil)
+				drawBlock(Block{Row: 0, Col: 1, Value: 5}, testGrid, ColorFallingBlock, canvas)
+			},
+		},
+		{
+			name: "negative",
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t,
+					Block{Row: 3, Col: 0, Value: -3},
+					Block{Row: 3, Col: 1, Value: -10},
+					Block{Row: 3, Col: 2, Value: -11},
+				), testGrid, canvas, nil)
+				drawBlock(Block{Row: 1, Col: 2, Value: -1}, testGrid, ColorFallingBlock, canvas)
+			},
+		},
+		{
+			name: "slam-trail",
+			draw: func(canvas Canvas) {
+				fx := &Effects{}
+				fx.Update(StepResult{
+					Slammed:   true,
+					SlamStart: []Block{{Row: 0, Col: 0}, {Row: 0, Col: 2}},
+					SlamEnd:   []Block{{Row: 3, Col: 0}, {Row: 2, Col: 2}},
+				})
+				fx.Draw(canvas, testGrid)
+				drawGrid(newTestGame(t,
+					Block{Row: 3, Col: 0, Value: 2},
+					Block{Row: 3, Col: 2, Value: 7},
+					Block{Row: 2, Col: 2, Value: 1},
+				), testGrid, canvas, nil)
+			},
+		},
+		{
+			name: "cleared",
+			draw: func(canvas Canvas) {
+				fx := &Effects{}
+				fx.Update(StepResult{Chain: []ChainStep{
+					{Cleared: []Block{{Row: 2, Col: 1}}},
+					{Cleared: []Block{{Row: 3, Col: 1}}},
+				}})
+				fx.Draw(canvas, testGrid)
+				drawGrid(newTestGame(t,
+					Block{Row: 3, Col: 0, Value: 4},
+					Block{Row: 3, Col: 1, Value: 2},
+				), testGrid, canvas, nil)
+			},
+		},
+		{
+			name: "score",
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t, Block{Row: 3, Col: 1, Value: 6}), testGrid, canvas, nil)
+				drawScore(1234, testGrid, canvas)
+			},
+		},
+		{
+			name: "level-up",
+			draw: func(canvas Canvas) {
+				fx := &Effects{}
+				fx.Update(StepResult{LevelUp: true, Level: 3})
+				drawGrid(newTestGame(t,
+					Block{Row: 3, Col: 0, Value: 4},
+					Block{Row: 2, Col: 0, Value: -7},
+				), testGrid, canvas, nil)
+				drawBlock(Block{Row: 1, Col: 1, Value: 2}, testGrid, ColorFallingBlock, canvas)
+				fx.drawLevelUp(canvas, testGrid)
+				drawScore(56, testGrid, canvas)
+				drawLevel(3, testGrid, canvas)
+			},
+		},
+		{
+			name: "ghost",
+			draw: func(canvas Canvas) {
+				game := newTestGame(t,
+					Block{Row: 3, Col: 0, Value: 5},
+					Block{Row: 3, Col: 1, Value: 12},
+					Block{Row: 3, Col: 2, Value: 8},
+				)
+				drawGrid(game, testGrid, canvas, []Landing{
+					{Type: LandedOnLiveBlock, Block: Block{Row: 3, Col: 0, Value: 3}, Value: 8},
+					{Type: LandedOnDeadBlock, Block: Block{Row: 2, Col: 1, Value: -2}, Value: -2},
+					{Type: LandedOnLiveBlock, Block: Block{Row: 3, Col: 2, Value: 6}, Value: 14, Dies: true},
+				})
+				drawBlock(Block{Row: 0, Col: 0, Value: 3}, testGrid, ColorFallingBlock, canvas)
+				drawBlock(Block{Row: 1, Col: 1, Value: -2}, testGrid, ColorFallingBlock, canvas)
+				drawBlock(Block{Row: 0, Col: 2, Value: 6}, testGrid, ColorFallingBlock, canvas)
+			},
+		},
+		{
+			name:  "side-panel",
+			panel: true,
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t), testGrid, canvas, nil)
+				drawSidePanel(canvas, testGrid, [][]Block{
+					{{Col: 0, Value: 3}, {Col: 2, Value: -2}},
+					{{Col: 1, Value: 10}},
+					{{Col: 0, Value: 1}, {Col: 1, Value: 2}, {Col: 2, Value: 3}},
+					{{Col: 1, Value: 4}},
+				}, []Block{{Col: 1, Value: -3}}, true)
+			},
+		},
+		{
+			name:  "hold-used",
+			panel: true,
+			draw: func(canvas Canvas) {
+				drawGrid(newTestGame(t), testGrid, canvas, nil)
+				drawSidePanel(canvas, testGrid, nil, []Block{{Col: 0, Value: 5}, {Col: 2, Value: 1}}, false)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			width := testGrid.PixelWidth()
+			if test.panel {
+				width += SidePanelWidth(testGrid)
+			}
+			canvas := NewImageCanvas(int(width), int(testGrid.PixelHeight()))
+			test.draw(canvas)
+			checkGolden(t, filepath.Join("testdata", test.name+".png"), canvas.Image())
+		})
+	}
+}
+
+// newTestGame returns a game on testGrid with the given blocks added.
+func newTestGame(t *testing.T, blocks ...Block) *GameState {
+	game := NewGameState(testGrid.Rows, testGrid.Cols, DefaultRules().MaxLiveValue)
+	for _, block := range blocks {
+		if err := game.AddBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return game
+}
+
+// checkGolden compares got with the PNG image in the golden file at path, or
+// overwrites the golden file if the -update flag is set.
+func checkGolden(t *testing.T, path string, got *image.RGBA) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !want.Bounds().Eq(got.Bounds()) {
+		t.Fatalf("image size = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	diffs := 0
+	var first image.Point
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				if diffs == 0 {
+					first = image.Pt(x, y)
+				}
+				diffs++
+			}
+		}
+	}
+	if diffs == 0 {
+		return
+	}
+
+	gotPath := filepath.Join(os.TempDir(), filepath.Base(path))
+	if err := ioutil.WriteFile(gotPath, buf.Bytes(), 0644); err != nil {
+		t.Log(err)
+	}
+	t.Errorf("%s: %d pixels differ, first at %v; got image written to %s (run go test -update if the change is intended)",
+		path, diffs, first, gotPath)
+}
+
+func TestTerminalGhostLabelsFitSquares(t *testing.T) {
+	grid := &Grid{Rows: testGrid.Rows, Cols: testGrid.Cols, SquareSize: 16}
+	canvas := NewTerminalCanvas(grid, 4)
+	game := newTestGame(t,
+		Block{Row: 3, Col: 0, Value: -3},
+		Block{Row: 3, Col: 1, Value: 12},
+		Block{Row: 3, Col: 2, Value: 8},
+	)
+	drawGrid(game, grid, canvas, []Landing{
+		{Type: LandedOnLiveBlock, Block: Block{Row: 3, Col: 0, Value: -2}, Value: -5},
+		{Type: LandedOnLiveBlock, Block: Block{Row: 3, Col: 1, Value: 2}, Value: 14, Dies: true},
+		{Type: LandedOnLiveBlock, Block: Block{Row: 3, Col: 2, Value: 1}, Value: 9},
+	})
+
+	// Labels that are wider than a square show only the value after landing,
+	// so that they stay within their own square.
+	var got []rune
+	for _, char := range canvas.chars[3] {
+		got = append(got, char.char)
+	}
+	if want := " -5  14 8>9 "; string(got) != want {
+		t.Errorf("bottom row = %q, want %q", string(got), want)
+	}
+}
@@ -0,0 +1,80 @@
+package numino
+
+import "time"
+
+const (
+	// TickDuration is the amount of real time simulated by each tick of a
+	// game.
+	TickDuration = time.Second / 60
+
+	// The most ticks a Timestep returns at once. Time beyond this is dropped,
+	// so that a long stall does not make the game skip ahead.
+	maxTicksPerUpdate = 30
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock that reports the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock whose time only changes when it is advanced.
+type ManualClock struct {
+	now time.Time
+}
+
+// Now implements the Clock interface.
+func (c *ManualClock) Now() time.Time {
+	return c.now
+}
+
+// Advance moves this clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// Timestep converts the real time that passes on a Clock into a whole number
+// of ticks, each TickDuration long.
+//
+// Time left over from one update is carried into the next, so that the
+// number of ticks is the same no matter how often Timestep is updated.
+type Timestep struct {
+	clock       Clock
+	last        time.Time
+	accumulated time.Duration
+}
+
+// NewTimestep returns a Timestep that starts counting time now.
+func NewTimestep(clock Clock) *Timestep {
+	return &Timestep{clock: clock, last: clock.Now()}
+}
+
+// Update returns the number of whole ticks that have elapsed since the last
+// update.
+func (t *Timestep) Update() int {
+	now := t.clock.Now()
+	t.accumulated += now.Sub(t.last)
+	t.last = now
+
+	ticks := int(t.accumulated / TickDuration)
+	t.accumulated -= time.Duration(ticks) * TickDuration
+	if ticks > maxTicksPerUpdate {
+		ticks = maxTicksPerUpdate
+	}
+	return ticks
+}
+
+// Reset discards the time that has elapsed since the last update, such as
+// time spent paused.
+func (t *Timestep) Reset() {
+	t.last = t.clock.Now()
+	t.accumulated = 0
+}
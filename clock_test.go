@@ -0,0 +1,53 @@
+package numino
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestep(t *testing.T) {
+	clock := &ManualClock{}
+	timestep := NewTimestep(clock)
+
+	// update advances clock by d and updates timestep, which must return
+	// want ticks.
+	update := func(d time.Duration, want int) {
+		t.Helper()
+		clock.Advance(d)
+		if got := timestep.Update(); got != want {
+			t.Errorf("Update() after %v = %d, want %d", d, got, want)
+		}
+	}
+
+	update(0, 0)
+	update(TickDuration, 1)
+	update(3*TickDuration, 3)
+
+	// Time left over from one update is carried into the next.
+	update(TickDuration/2, 0)
+	update(TickDuration/2, 1)
+	update(TickDuration*3/2, 1)
+	update(TickDuration/2, 1)
+
+	// Updates are capped, and the time beyond the cap is dropped.
+	update(100*TickDuration, maxTicksPerUpdate)
+	update(0, 0)
+
+	// Reset discards the time since the last update, including leftovers.
+	update(TickDuration*3/4, 0)
+	clock.Advance(10 * TickDuration)
+	timestep.Reset()
+	update(0, 0)
+	update(TickDuration/2, 0)
+	update(TickDuration/2, 1)
+}
+
+func TestManualClock(t *testing.T) {
+	clock := &ManualClock{}
+	start := clock.Now()
+	clock.Advance(time.Second)
+	clock.Advance(time.Millisecond)
+	if got := clock.Now().Sub(start); got != time.Second+time.Millisecond {
+		t.Errorf("clock advanced by %v, want %v", got, time.Second+time.Millisecond)
+	}
+}
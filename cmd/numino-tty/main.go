@@ -0,0 +1,257 @@
+// Command numino-tty plays numino in a terminal, without OpenGL.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kharland/numino"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	// The width of a cell on the board, in characters.
+	cellWidth = 4
+	// The size of a cell on the board in the pixel space that the game is
+	// drawn in. Any size works, as long as it is a multiple of cellWidth.
+	squareSize = 4 * cellWidth
+	// The help line shown below the board.
+	helpLine = "a/d:shift s:slam w:hold p:pause q:quit"
+)
+
+// Key bindings.
+const (
+	keyShiftLeft  = 'a'
+	keyShiftRight = 'd'
+	keySlam       = 's'
+	keyHold       = 'w'
+	keyPause      = 'p'
+	keyQuit       = 'q'
+	keyCtrlC      = 0x03
+)
+
+var (
+	gameFlags = numino.RegisterGameFlags(flag.CommandLine)
+	resume    = flag.Bool("continue", false, "continue the saved game instead of starting a new one")
+
+	// The game rules, set from gameFlags.
+	rules numino.Rules
+)
+
+func main() {
+	flag.Parse()
+	var err error
+	if rules, err = gameFlags.Rules(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid rules:", err)
+		os.Exit(2)
+	}
+
+	stats, err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if stats != nil {
+		fmt.Printf("GAME OVER! Score: %d Level: %d Seed: %d\n", stats.Score, stats.Level, stats.Seed)
+	}
+}
+
+// run plays a game in the terminal until it is over or the player quits.
+//
+// The stats of the game are returned if it is over.
+func run() (*numino.Stats, error) {
+	engine, err := newEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	term, err := openTerminal(os.Stdin, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	// Logging to the terminal while the game is drawn would garble it, so logs
+	// are kept until the terminal is restored.
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	// Restore the terminal even if the game panics, so that the panic can be
+	// read.
+	defer func() {
+		term.Restore()
+		log.SetOutput(os.Stderr)
+		os.Stderr.Write(logs.Bytes())
+		if r := recover(); r != nil {
+			panic(r)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	keys := term.Keys()
+	ticker := time.NewTicker(numino.TickDuration)
+	defer ticker.Stop()
+
+	runner := numino.NewRunner(engine, numino.SystemClock)
+	screen := &screen{term: term}
+	paused := false
+
+	for {
+		select {
+		case <-signals:
+			quitGame(engine)
+			return nil, nil
+		case key, ok := <-keys:
+			if !ok {
+				quitGame(engine)
+				return nil, nil
+			}
+			switch key {
+			case keyQuit, keyCtrlC:
+				quitGame(engine)
+				return nil, nil
+			case keyPause:
+				paused = !paused
+				runner.Pause()
+			case keyShiftLeft:
+				runner.Input(numino.ShiftLeftInput)
+			case keyShiftRight:
+				runner.Input(numino.ShiftRightInput)
+			case keySlam:
+				runner.Input(numino.SlamInput)
+			case keyHold:
+				runner.Input(numino.HoldInput)
+			}
+		case <-ticker.C:
+			if paused {
+				runner.Pause()
+			}
+			for _, step := range runner.Update() {
+				screen.Update(step)
+			}
+			if engine.IsOver() {
+				if _, err := numino.SaveReplay(engine.Replay()); err != nil {
+					log.Println("failed to save replay:", err)
+				}
+				if err := numino.DeleteSaveGame(); err != nil {
+					log.Println("failed to delete saved game:", err)
+				}
+				stats := engine.Stats()
+				return &stats, nil
+			}
+			screen.Draw(engine, paused)
+		}
+	}
+}
+
+// newEngine returns an Engine for a new game, or for the saved game if the
+// -continue flag is set.
+func newEngine() (*numino.Engine, error) {
+	if !*resume {
+		return numino.NewEngine(rules, gameFlags.NewSeed()), nil
+	}
+	save, err := numino.LoadSaveGame()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved game: %v", err)
+	}
+	return numino.RestoreEngine(save)
+}
+
+// quitGame saves the game in engine so that it can be continued later.
+func quitGame(engine *numino.Engine) {
+	if err := numino.StoreSaveGame(engine.Save()); err != nil {
+		log.Println("failed to save game:", err)
+	}
+}
+
+// screen draws a game to a terminal.
+type screen struct {
+	term *terminal
+	// The size of the terminal when it was last drawn.
+	width  int
+	height int
+	// The last frame written to the terminal.
+	lastFrame string
+	effects   numino.Effects
+}
+
+// Update is called for every step of the game.
+func (s *screen) Update(step numino.StepResult) {
+	s.effects.Update(step)
+}
+
+// Draw draws the game in engine, centered in the terminal.
+func (s *screen) Draw(engine *numino.Engine, paused bool) {
+	width, height, err := s.term.Size()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var out strings.Builder
+	if width != s.width || height != s.height {
+		// The terminal was resized, so the old frame may be anywhere.
+		out.WriteString(escResetStyle + escClearScreen)
+		s.width, s.height = width, height
+		s.lastFrame = ""
+	}
+
+	game := engine.Game()
+	// The board has a border, a status line above it and a help line below.
+	boardWidth := game.ColCount()*cellWidth + 2
+	frameWidth := boardWidth
+	if len(helpLine) > frameWidth {
+		frameWidth = len(helpLine)
+	}
+	frameHeight := game.RowCount() + 4
+	if width < frameWidth || height < frameHeight {
+		out.WriteString(escResetStyle + escClearScreen + moveTo(0, 0))
+		fmt.Fprintf(&out, "Terminal too small: need %dx%d", frameWidth, frameHeight)
+		fmt.Fprint(s.term.out, out.String())
+		// Clear the message once the terminal is big enough.
+		s.width = 0
+		return
+	}
+	top := (height - frameHeight) / 2
+	left := (width - boardWidth) / 2
+
+	status := fmt.Sprintf("Score: %d  Level: %d", engine.Score(), engine.Level())
+	if paused {
+		status += "  PAUSED"
+	}
+	if held := engine.HeldWave(); held != nil {
+		status += "  Hold:"
+		for _, block := range held {
+			status += fmt.Sprintf(" %d", block.Value)
+		}
+	}
+	out.WriteString(escResetStyle + moveTo(top, left))
+	fmt.Fprintf(&out, "%-*.*s", boardWidth, boardWidth, status)
+
+	grid := &numino.Grid{Rows: game.RowCount(), Cols: game.ColCount(), SquareSize: squareSize}
+	canvas := numino.NewTerminalCanvas(grid, cellWidth)
+	numino.DrawBoard(canvas, grid, engine, &s.effects)
+
+	border := fgColor(colornames.Black) + bgColor(colornames.Black)
+	out.WriteString(moveTo(top+1, left) + border + strings.Repeat(" ", boardWidth))
+	for row, line := range canvas.Lines() {
+		out.WriteString(moveTo(top+2+row, left) + border + " " + line + border + " ")
+	}
+	out.WriteString(moveTo(top+2+game.RowCount(), left) + border + strings.Repeat(" ", boardWidth))
+
+	out.WriteString(escResetStyle + moveTo(top+3+game.RowCount(), (width-frameWidth)/2))
+	out.WriteString(helpLine)
+
+	// Most ticks change nothing on screen, so only write changed frames.
+	if frame := out.String(); frame != s.lastFrame {
+		fmt.Fprint(s.term.out, frame)
+		s.lastFrame = frame
+	}
+}
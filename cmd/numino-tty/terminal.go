@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences.
+const (
+	escAltScreen     = "\x1b[?1049h"
+	escMainScreen    = "\x1b[?1049l"
+	escHideCursor    = "\x1b[?25l"
+	escShowCursor    = "\x1b[?25h"
+	escClearScreen   = "\x1b[2J"
+	escResetStyle    = "\x1b[0m"
+	escCursorFormat  = "\x1b[%d;%dH"
+	escBgColorFormat = "\x1b[48;2;%d;%d;%dm"
+	escFgColorFormat = "\x1b[38;2;%d;%d;%dm"
+)
+
+// terminal is a terminal in raw mode, showing the alternate screen.
+type terminal struct {
+	in        *os.File
+	out       *os.File
+	state     *term.State
+	restoring sync.Once
+}
+
+// openTerminal puts the terminal attached to in and out into raw mode and
+// switches to the alternate screen.
+//
+// Restore must be called to return the terminal to its original state.
+func openTerminal(in *os.File, out *os.File) (*terminal, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("numino-tty must be run in a terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprint(out, escAltScreen+escHideCursor+escClearScreen)
+	return &terminal{in: in, out: out, state: state}, nil
+}
+
+// Size returns the width and height of the terminal, in characters.
+func (t *terminal) Size() (int, int, error) {
+	return term.GetSize(int(t.out.Fd()))
+}
+
+// Restore returns the terminal to the state it was in before openTerminal.
+// It is safe to call Restore more than once.
+func (t *terminal) Restore() {
+	t.restoring.Do(func() {
+		fmt.Fprint(t.out, escResetStyle+escShowCursor+escMainScreen)
+		term.Restore(int(t.in.Fd()), t.state)
+	})
+}
+
+// Keys reads key presses from the terminal and sends them on the returned
+// channel. The channel is closed when the terminal can no longer be read.
+func (t *terminal) Keys() <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 16)
+		for {
+			n, err := t.in.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, b := range buf[:n] {
+				keys <- b
+			}
+		}
+	}()
+	return keys
+}
+
+func moveTo(row int, col int) string {
+	// Terminal rows and columns start at 1.
+	return fmt.Sprintf(escCursorFormat, row+1, col+1)
+}
+
+func bgColor(c color.RGBA) string {
+	return fmt.Sprintf(escBgColorFormat, c.R, c.G, c.B)
+}
+
+func fgColor(c color.RGBA) string {
+	return fmt.Sprintf(escFgColorFormat, c.R, c.G, c.B)
+}
@@ -1,60 +1,50 @@
 package main
 
 import (
-	"github.com/faiface/pixel"
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/kharland/numino"
 )
 
-// Game configuration options.
-const (
-	// The number of rows to start the game with
-	numRows = 9
-	// The number of cols to start the game with
-	numCols = 6
+var (
+	gameFlags = numino.RegisterGameFlags(flag.CommandLine)
+
+	// The game rules, set from gameFlags.
+	rules numino.Rules
 )
 
 func run() {
-	grid := &numino.Grid{Cols: numCols, Rows: numRows, SquareSize: 50}
+	grid := numino.MenuGrid()
 	win, err := pixelgl.NewWindow(pixelgl.WindowConfig{
 		Title:  "Numino",
-		Bounds: pixel.R(0, 0, grid.PixelWidth(), grid.PixelHeight()),
+		Bounds: numino.WindowBounds(grid),
+		VSync:  true,
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	// The various numino view.
-	menuView := numino.ViewMenu
-	gameView := numino.ViewGame
-	controlsView := numino.ViewControls
-
-	// The channel used by views to signal that they are done. A view should
-	// signal the channel once it is done, with a value specifying the next
-	// view to load.
-	done := make(chan numino.GoToCmd)
-	defer close(done)
-
-	// Start off at the main menu.
-	go menuView(win, grid, done)
-
-	for evt := range done {
-		switch evt {
-		case numino.GoToExit:
-			return
-		case numino.GoToNewGame:
-			go gameView(win, grid, done)
-			break
-		case numino.GoToMenu:
-			go menuView(win, grid, done)
-			break
-		case numino.GoToControls:
-			go controlsView(win, grid, done)
-			break
-		}
-	}
+	// Start off at the main menu, which pops when the player exits.
+	router := numino.NewRouter(win, grid)
+	numino.Run(router, numino.MenuRoute, numino.MenuParams{
+		Rules:   rules,
+		NewSeed: gameFlags.NewSeed,
+	})
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay2gif" {
+		os.Exit(replay2gif(os.Args[2:]))
+	}
+
+	flag.Parse()
+	var err error
+	if rules, err = gameFlags.Rules(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid rules:", err)
+		os.Exit(2)
+	}
 	pixelgl.Run(run)
 }
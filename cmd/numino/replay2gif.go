@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"os"
+	"strings"
+
+	"github.com/kharland/numino"
+)
+
+const (
+	// How long the last frame of a GIF is shown for, in hundredths of a
+	// second.
+	gifEndDelay = 300
+	// The number of hundredths of a second in a tick.
+	gifDelayPerTick = 100.0 / 60
+)
+
+// replay2gif runs the replay2gif subcommand with the given arguments, and
+// returns the exit code.
+func replay2gif(args []string) int {
+	fs := flag.NewFlagSet("replay2gif", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: numino replay2gif [flags] [replay]")
+		fmt.Fprintln(fs.Output(), "\nRenders a replay to an animated GIF. If no replay is given, the newest saved replay is used.")
+		fs.PrintDefaults()
+	}
+	out := fs.String("o", "", "path of the GIF to write. Defaults to the replay's path with a .gif extension")
+	every := fs.Int("every", 3, "draw a frame every this many ticks")
+	squareSize := fs.Float64("size", 50, "size of each cell, in pixels")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *every < 1 || *squareSize < 1 || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		paths, err := numino.ListReplays()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "no saved replays")
+			return 1
+		}
+		path = paths[0]
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".replay") + ".gif"
+	}
+
+	replay, err := numino.LoadReplay(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := writeReplayGIF(*out, replay, *every, *squareSize); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("wrote", *out)
+	return 0
+}
+
+// writeReplayGIF plays replay without a window and writes every nth tick to a
+// GIF at path.
+func writeReplayGIF(path string, replay *numino.Replay, every int, squareSize float64) error {
+	grid := &numino.Grid{Rows: replay.Rules.Rows, Cols: replay.Rules.Cols, SquareSize: squareSize}
+	palette := replayPalette()
+
+	player := numino.NewReplayPlayer(replay)
+	effects := &numino.Effects{}
+	anim := &gif.GIF{}
+	// Ticks are not a whole number of hundredths of a second, so frame delays
+	// are rounded from the total time elapsed to keep the GIF in sync.
+	ticks, delayed := 0, 0
+	addFrame := func() {
+		canvas := numino.NewGridImageCanvas(grid)
+		numino.DrawGame(canvas, grid, player.Engine(), effects)
+		frame := image.NewPaletted(canvas.Image().Bounds(), palette)
+		draw.Draw(frame, frame.Bounds(), canvas.Image(), image.Point{}, draw.Src)
+
+		delay := int(float64(ticks)*gifDelayPerTick+0.5) - delayed
+		delayed += delay
+		if n := len(anim.Image); n > 0 {
+			anim.Delay[n-1] += delay
+			// Most frames are the same as the last, so extend the last frame
+			// instead of adding another.
+			if bytes.Equal(anim.Image[n-1].Pix, frame.Pix) {
+				return
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 0)
+	}
+
+	addFrame()
+	for !player.Done() {
+		for i := 0; i < every && !player.Done(); i++ {
+			effects.Update(player.Step())
+			ticks++
+		}
+		addFrame()
+	}
+	anim.Delay[len(anim.Delay)-1] += gifEndDelay
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replayPalette returns every color that a game can be drawn with.
+//
+// Ghosts are translucent, and are drawn over the board's background, the
+// effects, a block they would merge with or, on a small grid, the value of the
+// block above them. The level up banner is drawn over all of those, so the
+// palette has every blend of them.
+func replayPalette() color.Palette {
+	opaque := []color.RGBA{
+		numino.ColorBg,
+		numino.ColorFallingBlock,
+		numino.ColorDeadBlock,
+		numino.ColorLiveBlock,
+		numino.ColorSlamTrail,
+		numino.ColorCleared,
+		numino.ColorText,
+	}
+	colors := append([]color.RGBA(nil), opaque...)
+	for _, ghost := range []color.RGBA{numino.ColorGhost, numino.ColorGhostDead} {
+		for _, bg := range opaque {
+			colors = append(colors, over(ghost, bg))
+		}
+	}
+
+	var palette color.Palette
+	for _, c := range colors {
+		palette = append(palette, c, over(numino.ColorLevelUp, c))
+	}
+	return palette
+}
+
+// over returns the color of the premultiplied color c drawn over the opaque
+// color bg, blended the same way as on an ImageCanvas.
+func over(c, bg color.RGBA) color.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, bg)
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Over)
+	return img.RGBAAt(0, 0)
+}
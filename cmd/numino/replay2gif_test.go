@@ -0,0 +1,55 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/kharland/numino"
+)
+
+func TestReplayPaletteHasEveryColor(t *testing.T) {
+	palette := replayPalette()
+	inPalette := make(map[color.RGBA]bool)
+	for _, c := range palette {
+		inPalette[c.(color.RGBA)] = true
+	}
+
+	// A game that slams often merges and levels up, so that it draws ghosts
+	// over blocks and the level up banner over the board.
+	engine := numino.NewEngine(numino.DefaultRules(), 7)
+	grid := &numino.Grid{Rows: engine.Rules().Rows, Cols: engine.Rules().Cols, SquareSize: 20}
+	effects := &numino.Effects{}
+	var merged, leveledUp bool
+	for tick := 1; tick <= 3000 && !engine.IsOver(); tick++ {
+		input := numino.NoInput
+		switch {
+		case tick%90 == 0:
+			input = numino.SlamInput
+		case tick%7 == 0:
+			input = numino.ShiftLeftInput
+		case tick%11 == 0:
+			input = numino.ShiftRightInput
+		}
+		step := engine.Step(input)
+		effects.Update(step)
+		merged = merged || step.Merged
+		leveledUp = leveledUp || step.LevelUp
+		if tick%5 != 0 {
+			continue
+		}
+
+		canvas := numino.NewGridImageCanvas(grid)
+		numino.DrawGame(canvas, grid, engine, effects)
+		img := canvas.Image()
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				if c := img.RGBAAt(x, y); !inPalette[c] {
+					t.Fatalf("tick %d: color %v at (%d, %d) is not in the palette", tick, c, x, y)
+				}
+			}
+		}
+	}
+	if !merged || !leveledUp {
+		t.Errorf("game merged = %v and leveled up = %v, want both", merged, leveledUp)
+	}
+}
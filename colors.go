@@ -12,5 +12,17 @@ var (
 	ColorDeadBlock               = colornames.Tomato
 	ColorLiveBlock               = colornames.Aquamarine
 	ColorSlamTrail               = colornames.Cadetblue
+	ColorCleared                 = colornames.Gold
 	ColorMenuOption              = colornames.Crimson
+	ColorPauseOverlay            = color.RGBA{A: 0x99}
+	ColorText                    = colornames.Black
+	ColorPanel                   = colornames.Lavender
+	ColorPanelSlot               = colornames.Aliceblue
+	ColorHoldUsed                = colornames.Lightsteelblue
+	// ColorGhost is ColorFallingBlock at 40% opacity, ColorGhostDead is
+	// ColorDeadBlock at 70% opacity and ColorLevelUp is ColorCleared at 70%
+	// opacity.
+	ColorGhost     = color.RGBA{R: 0x28, G: 0x3c, B: 0x5f, A: 0x66}
+	ColorGhostDead = color.RGBA{R: 0xb2, G: 0x45, B: 0x31, A: 0xb2}
+	ColorLevelUp   = color.RGBA{R: 0xb2, G: 0x96, A: 0xb2}
 )
@@ -20,3 +20,8 @@ func (c *counter) Update(ticks float64) bool {
 	}
 	return elapsed
 }
+
+// Reset starts a new time quantum at ticks.
+func (c *counter) Reset(ticks float64) {
+	c.lastQuantum = ticks
+}
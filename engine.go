@@ -0,0 +1,350 @@
+package numino
+
+import (
+	"log"
+	"time"
+)
+
+// Input is an action the player takes during a single Step of an Engine.
+type Input int
+
+const (
+	// NoInput means the player did nothing.
+	NoInput Input = iota
+	// ShiftLeftInput shifts the falling blocks to the left.
+	ShiftLeftInput
+	// ShiftRightInput shifts the falling blocks to the right.
+	ShiftRightInput
+	// SlamInput slams the falling blocks to the bottom of the grid.
+	SlamInput
+	// HoldInput swaps the falling blocks with the held wave.
+	HoldInput
+)
+
+// StepResult describes what happened during a single Step of an Engine.
+type StepResult struct {
+	// Shifted is true if the falling blocks were shifted.
+	Shifted bool
+	// Held is true if the falling blocks were swapped with the held wave.
+	Held bool
+	// Slammed is true if the falling blocks were slammed. SlamStart and
+	// SlamEnd hold the positions of the falling blocks before and after the
+	// slam, in the same order.
+	Slammed   bool
+	SlamStart []Block
+	SlamEnd   []Block
+	// Landed holds the blocks that were added to the grid.
+	Landed []Block
+	// Merged is true if a block landed on a live block.
+	Merged bool
+	// Died is true if a landed block is now dead.
+	Died bool
+	// Chain holds the steps taken to resolve the grid after blocks landed,
+	// if any landed blocks reached zero.
+	Chain []ChainStep
+	// LevelUp is true if the game reached a new level, and Level is the level
+	// of the game after this step.
+	LevelUp bool
+	Level   int
+	// Spawned is true if a new wave of falling blocks was generated.
+	Spawned bool
+	// Over is true if the game is over.
+	Over bool
+}
+
+// Stats summarizes a game.
+type Stats struct {
+	Seed int64
+	// Rows and Cols are the size of the game's grid.
+	Rows int
+	Cols int
+	// Mode is the game mode of the game's rules.
+	Mode         string
+	Score        int
+	BlocksLanded int
+	// Merges is the number of blocks that landed on a live block.
+	Merges int
+	// Deaths is the number of blocks that died.
+	Deaths int
+	// Zeroes is the number of cells that were brought to zero.
+	Zeroes int
+	// MaxChain is the most steps a single chain took.
+	MaxChain int
+	// Points is how the score was earned.
+	Points ScorePoints
+	// Level is the level the game reached.
+	Level int
+	// LongestSurvival is the longest time that passed without a block dying.
+	LongestSurvival time.Duration
+	// PeakSpeed is the fastest speed the blocks fell at, relative to their
+	// starting speed.
+	PeakSpeed float64
+	// Duration is the time spent playing the game, not counting pauses.
+	Duration time.Duration
+}
+
+// Engine runs a game of numino without a window.
+//
+// Each call to Step advances the game by a single tick, which simulates
+// TickDuration of real time. Use a Timestep to decide how many ticks to step.
+type Engine struct {
+	rules         Rules
+	game          *GameState
+	fallingBlocks *FallingBlocks
+	seed          int64
+	ticks         float64
+	points        ScorePoints
+	level         int
+	// The inputs applied during this game, for replays.
+	events []ReplayEvent
+
+	// Counters for Stats.
+	blocksLanded    int
+	merges          int
+	deaths          int
+	zeroes          int
+	maxChain        int
+	lastDeath       float64
+	longestSurvival float64
+	peakSpeed       float64
+}
+
+// NewEngine returns an Engine for a new game with the given rules.
+//
+// The same rules, seed and sequence of inputs always produce the same game.
+// The rules must be valid.
+func NewEngine(rules Rules, seed int64) *Engine {
+	return &Engine{
+		rules:         rules,
+		game:          NewGameState(rules.Rows, rules.Cols, rules.MaxLiveValue),
+		fallingBlocks: NewFallingBlocks(rules, seed),
+		seed:          seed,
+		level:         1,
+		peakSpeed:     1,
+	}
+}
+
+// Rules returns the rules of this game.
+func (e *Engine) Rules() Rules {
+	return e.rules
+}
+
+// Seed returns the seed used to generate this game's blocks.
+func (e *Engine) Seed() int64 {
+	return e.seed
+}
+
+// Game returns the blocks that have been placed on the grid.
+func (e *Engine) Game() *GameState {
+	return e.game
+}
+
+// FallingBlocks returns a copy of the blocks under player control.
+func (e *Engine) FallingBlocks() []Block {
+	return e.fallingBlocks.Blocks()
+}
+
+// PredictSlam returns where each falling block would land if the blocks were
+// slammed, in the same order as FallingBlocks. The game is not changed.
+func (e *Engine) PredictSlam() []Landing {
+	return e.fallingBlocks.PredictSlam(e.game)
+}
+
+// UpcomingWaves returns copies of the waves of blocks that will fall after the
+// current one, in order.
+func (e *Engine) UpcomingWaves() [][]Block {
+	return e.fallingBlocks.Upcoming()
+}
+
+// HeldWave returns a copy of the wave in the hold slot, or nil if no wave is
+// held.
+func (e *Engine) HeldWave() []Block {
+	return e.fallingBlocks.Held()
+}
+
+// CanHold returns true iff the falling blocks can be swapped with the held
+// wave.
+func (e *Engine) CanHold() bool {
+	return e.fallingBlocks.CanHold()
+}
+
+// Ticks returns the number of ticks that have elapsed in this game.
+func (e *Engine) Ticks() float64 {
+	return e.ticks
+}
+
+// Score returns the score of this game, according to its rules' scoring.
+func (e *Engine) Score() int {
+	return e.points.Total()
+}
+
+// Level returns the level this game has reached. Levels start at 1.
+func (e *Engine) Level() int {
+	return e.level
+}
+
+// IsOver returns true iff this game is over.
+func (e *Engine) IsOver() bool {
+	return e.game.IsOver()
+}
+
+// Stats returns a summary of this game so far.
+func (e *Engine) Stats() Stats {
+	longestSurvival := e.longestSurvival
+	if e.ticks-e.lastDeath > longestSurvival {
+		longestSurvival = e.ticks - e.lastDeath
+	}
+	return Stats{
+		Seed:            e.seed,
+		Rows:            e.game.RowCount(),
+		Cols:            e.game.ColCount(),
+		Mode:            e.rules.Mode(),
+		Score:           e.points.Total(),
+		BlocksLanded:    e.blocksLanded,
+		Merges:          e.merges,
+		Deaths:          e.deaths,
+		Zeroes:          e.zeroes,
+		MaxChain:        e.maxChain,
+		Points:          e.points,
+		Level:           e.level,
+		LongestSurvival: time.Duration(longestSurvival) * TickDuration,
+		PeakSpeed:       e.peakSpeed,
+		Duration:        time.Duration(e.ticks) * TickDuration,
+	}
+}
+
+// Replay returns a recording of this game so far.
+func (e *Engine) Replay() *Replay {
+	events := make([]ReplayEvent, len(e.events))
+	copy(events, e.events)
+	return &Replay{
+		Version: replayVersion,
+		Seed:    e.seed,
+		Rules:   e.rules,
+		Ticks:   int(e.ticks),
+		Events:  events,
+	}
+}
+
+// Step advances this game by one tick after applying the given input.
+//
+// Calling Step after the game is over does nothing.
+func (e *Engine) Step(input Input) StepResult {
+	var result StepResult
+	if e.game.IsOver() {
+		result.Over = true
+		return result
+	}
+
+	e.ticks++
+	if input != NoInput {
+		e.events = append(e.events, ReplayEvent{Tick: int(e.ticks), Input: input})
+	}
+
+	switch input {
+	case SlamInput:
+		result.Slammed = true
+		result.SlamStart = e.fallingBlocks.Blocks()
+		e.fallingBlocks.Slam(e.game)
+		result.SlamEnd = e.fallingBlocks.Blocks()
+	case ShiftLeftInput:
+		result.Shifted = true
+		e.fallingBlocks.ShiftLeft(e.game)
+	case ShiftRightInput:
+		result.Shifted = true
+		e.fallingBlocks.ShiftRight(e.game)
+	case HoldInput:
+		result.Held = e.fallingBlocks.Hold(e.ticks)
+	}
+
+	// Update sub systems. Slammed blocks already overlap the cells they land
+	// in, so they must not fall any further this tick.
+	if !result.Slammed {
+		e.fallingBlocks.Update(e.ticks, e.game)
+	}
+
+	// Add landed blocks to the grid.
+	var merged, died int
+	for _, block := range e.fallingBlocks.Blocks() {
+		landingType, lrow, lcol := e.fallingBlocks.DescribeLanding(block, e.game)
+		switch landingType {
+		case Unlanded:
+			continue
+		case LandedOnLiveBlock:
+			result.Merged = true
+			merged++
+			e.merges++
+		}
+
+		e.blocksLanded++
+		newBlock := Block{Row: lrow, Col: lcol, Value: block.Value}
+		if err := e.game.AddBlock(newBlock); err != nil {
+			log.Fatal(err)
+		}
+		if e.game.IsDead(newBlock.Row, newBlock.Col) {
+			result.Died = true
+			died++
+			e.recordDeath()
+		}
+		result.Landed = append(result.Landed, newBlock)
+		e.fallingBlocks.Remove(block.Row, block.Col)
+	}
+
+	// Clear the blocks that reached zero, and resolve any chain that follows.
+	var cleared []Block
+	for _, block := range result.Landed {
+		if e.game.IsEmpty(block.Row, block.Col) {
+			cleared = append(cleared, Block{Row: block.Row, Col: block.Col})
+		}
+	}
+	result.Chain = e.game.Resolve(cleared)
+	for _, step := range result.Chain {
+		e.zeroes += len(step.Cleared)
+		for range step.Merged {
+			result.Merged = true
+			e.merges++
+		}
+		for range step.Died {
+			result.Died = true
+			e.recordDeath()
+		}
+	}
+	if len(result.Chain) > e.maxChain {
+		e.maxChain = len(result.Chain)
+	}
+	e.points.Add(e.rules.Scoring.Points(len(result.Landed), merged, died, result.Chain))
+
+	// Level up once enough blocks have landed or points have been scored. The
+	// level never goes down, even if points are lost.
+	if level := e.rules.Levels.Reached(e.blocksLanded, e.points.Total()); level > e.level {
+		// Levels from a table can be slower than the ones before them, and
+		// a single step can pass through several levels.
+		for n := e.level + 1; n <= level; n++ {
+			if speed := e.rules.StartingTicksPerStep / e.rules.Level(n).TicksPerStep; speed > e.peakSpeed {
+				e.peakSpeed = speed
+			}
+		}
+		e.level = level
+		e.fallingBlocks.SetLevel(e.rules.Level(level))
+		result.LevelUp = true
+	}
+	result.Level = e.level
+
+	// If all blocks have landed, generate a new wave of blocks.
+	if e.fallingBlocks.Length() == 0 {
+		e.fallingBlocks.NextWave(e.ticks)
+		result.Spawned = true
+	}
+
+	result.Over = e.game.IsOver()
+	return result
+}
+
+// recordDeath updates the longest survival when a block dies.
+func (e *Engine) recordDeath() {
+	e.deaths++
+	if e.ticks-e.lastDeath > e.longestSurvival {
+		e.longestSurvival = e.ticks - e.lastDeath
+	}
+	e.lastDeath = e.ticks
+}
@@ -0,0 +1,88 @@
+package numino
+
+import "testing"
+
+func TestStatsPeakSpeed(t *testing.T) {
+	// Level 2 is twice as fast as the start, and level 3 is slower again.
+	rules := DefaultRules()
+	rules.StartingTicksPerStep = 100
+	rules.SpeedupFactor = 1
+	rules.Levels = LevelCurve{
+		BlocksPerLevel: 1,
+		Levels: []Level{
+			{TicksPerStep: 50, SpawnChance: 0.5, MinValue: 1, MaxValue: 3},
+			{TicksPerStep: 200, SpawnChance: 0.5, MinValue: 1, MaxValue: 3},
+		},
+	}
+
+	engine := NewEngine(rules, 1)
+	if got := engine.Stats().PeakSpeed; got != 1 {
+		t.Errorf("PeakSpeed at start = %v, want 1", got)
+	}
+	for engine.Level() < 3 && !engine.IsOver() {
+		engine.Step(SlamInput)
+	}
+	if engine.Level() < 3 {
+		t.Fatalf("game ended at level %d, want level 3", engine.Level())
+	}
+	if got := engine.Stats().PeakSpeed; got != 2 {
+		t.Errorf("PeakSpeed at level %d = %v, want 2", engine.Level(), got)
+	}
+	if got := saveAndRestore(t, engine).Stats().PeakSpeed; got != 2 {
+		t.Errorf("PeakSpeed of restored game = %v, want 2", got)
+	}
+}
+
+func TestStatsLongestSurvival(t *testing.T) {
+	engine := NewEngine(DefaultRules(), 1)
+	for tick := 0; tick < 90; tick++ {
+		engine.Step(NoInput)
+	}
+	if got, want := engine.Stats().LongestSurvival, 90*TickDuration; got != want {
+		t.Errorf("LongestSurvival = %v, want %v", got, want)
+	}
+}
+
+func TestEnginePredictSlam(t *testing.T) {
+	engine := NewEngine(DefaultRules(), 1)
+	var slams, merges int
+	for slams < 40 && !engine.IsOver() {
+		if len(engine.FallingBlocks()) == 0 {
+			engine.Step(NoInput)
+			continue
+		}
+		// Shift some waves, so that blocks land on each other.
+		if slams%3 == 1 {
+			engine.Step(ShiftLeftInput)
+		}
+
+		landings := engine.PredictSlam()
+		result := engine.Step(SlamInput)
+		slams++
+		if len(landings) != len(result.Landed) {
+			t.Fatalf("slam %d: PredictSlam() = %+v, but %+v landed", slams, landings, result.Landed)
+		}
+		for i, landing := range landings {
+			if landing.Block != result.Landed[i] {
+				t.Errorf("slam %d: PredictSlam()[%d].Block = %+v, but %+v landed", slams, i, landing.Block, result.Landed[i])
+			}
+			if landing.Type == LandedOnLiveBlock {
+				merges++
+			}
+			// A chain changes the grid after the blocks land.
+			if len(result.Chain) > 0 {
+				continue
+			}
+			row, col := landing.Block.Row, landing.Block.Col
+			if got := engine.Game().ValueAt(row, col); got != landing.Value {
+				t.Errorf("slam %d: PredictSlam()[%d].Value = %d, but the cell holds %d", slams, i, landing.Value, got)
+			}
+			if got := engine.Game().IsDead(row, col); got != landing.Dies {
+				t.Errorf("slam %d: PredictSlam()[%d].Dies = %v, but IsDead is %v", slams, i, landing.Dies, got)
+			}
+		}
+	}
+	if merges == 0 {
+		t.Errorf("no block landed on a live block in %d slams", slams)
+	}
+}
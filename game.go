@@ -11,6 +11,8 @@ type GameState struct {
 	blocks [][]int
 	// blockState tracks whether a block is dead or live.
 	blockState [][]BlockState
+	// The maximum value a block can hold before it is marked as dead.
+	maxLiveValue int
 }
 
 // BlockState determines whether a block is dead or live.
@@ -21,17 +23,16 @@ const (
 	DeadBlock BlockState = true
 	// LiveBlock describes a block that can be modified.
 	LiveBlock BlockState = false
-
-	// The maximum value a block can hold before it is marked as dead.
-	maxLiveValue = 10
 )
 
 // NewGameState returns a GameState with the given number of rows and columns.
-// All blocks are initially alive and empty.
-func NewGameState(rows int, cols int) *GameState {
+// All blocks are initially alive and empty. A block dies when its absolute
+// value exceeds maxLiveValue.
+func NewGameState(rows int, cols int, maxLiveValue int) *GameState {
 	g := &GameState{
-		blocks:     make([][]int, rows),
-		blockState: make([][]BlockState, rows),
+		blocks:       make([][]int, rows),
+		blockState:   make([][]BlockState, rows),
+		maxLiveValue: maxLiveValue,
 	}
 	for i := 0; i < rows; i++ {
 		g.blocks[i] = make([]int, cols)
@@ -81,21 +82,127 @@ func (gs *GameState) ValueAt(row int, col int) int {
 // If the block overlaps a live block, its value is added to the live block's
 // value. If the new value is outside the allowed bounds, the block becomes dead.
 func (gs *GameState) AddBlock(block Block) error {
-	fmt.Println("Adding block", block)
-	fmt.Println("blockState", len(gs.blockState), len(gs.blockState[0]), len(gs.blockState[block.Row]))
 	if block.Row >= gs.RowCount() {
-		return fmt.Errorf("invalid row", block.Row)
+		return fmt.Errorf("invalid row: %d", block.Row)
 	}
 
 	if gs.IsDead(block.Row, block.Col) {
-		return fmt.Errorf("space is not empty", block.Row, block.Col)
+		return fmt.Errorf("space is not empty: %d, %d", block.Row, block.Col)
 	}
 
 	gs.blocks[block.Row][block.Col] += block.Value
 	// Turn cell dead id value is out of bounds.
-	if math.Abs(float64(gs.blocks[block.Row][block.Col])) > maxLiveValue {
+	if !gs.IsLiveValue(gs.blocks[block.Row][block.Col]) {
 		gs.blockState[block.Row][block.Col] = DeadBlock
 	}
 
 	return nil
 }
+
+// IsLiveValue returns true iff a block with the given value is live.
+func (gs *GameState) IsLiveValue(value int) bool {
+	return math.Abs(float64(value)) <= float64(gs.maxLiveValue)
+}
+
+// ChainStep is a single step of resolving the grid after blocks land.
+type ChainStep struct {
+	// Cleared holds the cells that reached zero and were cleared.
+	Cleared []Block
+	// Fell holds the blocks that fell into the holes below them.
+	Fell []BlockFall
+	// Merged holds the blocks that falling blocks merged with, with their
+	// new values.
+	Merged []Block
+	// Died holds the merged blocks that are now dead.
+	Died []Block
+}
+
+// BlockFall describes a block that fell during a ChainStep.
+type BlockFall struct {
+	// Block is the block before it fell.
+	Block Block
+	// Row is the row the block fell to. If the block merged, this is the row
+	// of the block it merged with.
+	Row int
+}
+
+// Resolve clears the given cells, which have reached zero, and lets the
+// blocks above them fall.
+//
+// The lowest block above a hole falls onto the block below the hole, and
+// merges with it if both are live. The blocks above it fall with it and come
+// to rest on top. Blocks that merge to zero are cleared in the next step, and
+// so on until no cells are cleared. The steps are returned in order.
+func (gs *GameState) Resolve(cleared []Block) []ChainStep {
+	var steps []ChainStep
+	for len(cleared) > 0 {
+		step := ChainStep{Cleared: cleared}
+		for _, block := range cleared {
+			gs.blocks[block.Row][block.Col] = 0
+			gs.blockState[block.Row][block.Col] = LiveBlock
+		}
+		cleared = nil
+
+		for col := 0; col < gs.ColCount(); col++ {
+			gs.fall(col, &step)
+		}
+		for _, block := range step.Merged {
+			if block.Value == 0 {
+				cleared = append(cleared, block)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// fall lets the blocks in the given column fall into the holes below them,
+// and records what happened in step.
+func (gs *GameState) fall(col int, step *ChainStep) {
+	// The number of rows that the blocks above are falling by, or -1 if the
+	// block below did not fall.
+	drop := -1
+	for row := gs.RowCount() - 2; row >= 0; row-- {
+		if gs.IsEmpty(row, col) {
+			drop = -1
+			continue
+		}
+		value, state := gs.blocks[row][col], gs.blockState[row][col]
+		block := Block{Row: row, Col: col, Value: value}
+
+		if drop < 0 {
+			// This block rests on the block below it, unless there is a hole.
+			to := row
+			for to+1 < gs.RowCount() && gs.IsEmpty(to+1, col) {
+				to++
+			}
+			if to == row {
+				continue
+			}
+			gs.blocks[row][col], gs.blockState[row][col] = 0, LiveBlock
+
+			below := to + 1
+			if state == LiveBlock && below < gs.RowCount() && !gs.IsDead(below, col) {
+				gs.AddBlock(Block{Row: below, Col: col, Value: value})
+				merged := Block{Row: below, Col: col, Value: gs.blocks[below][col]}
+				step.Merged = append(step.Merged, merged)
+				if gs.IsDead(below, col) {
+					step.Died = append(step.Died, merged)
+				}
+				step.Fell = append(step.Fell, BlockFall{Block: block, Row: below})
+				// The blocks above come to rest on the merged block.
+				drop = to - row + 1
+				continue
+			}
+			gs.blocks[to][col], gs.blockState[to][col] = value, state
+			step.Fell = append(step.Fell, BlockFall{Block: block, Row: to})
+			drop = to - row
+			continue
+		}
+
+		// This block falls with the block below it.
+		gs.blocks[row][col], gs.blockState[row][col] = 0, LiveBlock
+		gs.blocks[row+drop][col], gs.blockState[row+drop][col] = value, state
+		step.Fell = append(step.Fell, BlockFall{Block: block, Row: row + drop})
+	}
+}
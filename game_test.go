@@ -0,0 +1,203 @@
+package numino
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		// blocks are added to a game on testGrid, then cleared is resolved.
+		blocks  []Block
+		cleared []Block
+		// want is the board after resolving, as returned by boardString.
+		want  []string
+		steps []ChainStep
+	}{
+		{
+			name: "single hole",
+			blocks: []Block{
+				{Row: 0, Col: 0, Value: 1},
+				{Row: 1, Col: 0, Value: 4},
+				{Row: 3, Col: 0, Value: 2},
+			},
+			cleared: []Block{{Row: 2, Col: 0}},
+			want: []string{
+				". . .",
+				". . .",
+				"1 . .",
+				"6 . .",
+			},
+			steps: []ChainStep{{
+				Cleared: []Block{{Row: 2, Col: 0}},
+				Fell: []BlockFall{
+					{Block: Block{Row: 1, Col: 0, Value: 4}, Row: 3},
+					{Block: Block{Row: 0, Col: 0, Value: 1}, Row: 2},
+				},
+				Merged: []Block{{Row: 3, Col: 0, Value: 6}},
+			}},
+		},
+		{
+			name: "merge to zero continues chain",
+			blocks: []Block{
+				{Row: 0, Col: 1, Value: 5},
+				{Row: 1, Col: 1, Value: -3},
+				{Row: 3, Col: 1, Value: 3},
+			},
+			cleared: []Block{{Row: 2, Col: 1}},
+			want: []string{
+				". . .",
+				". . .",
+				". . .",
+				". 5 .",
+			},
+			steps: []ChainStep{
+				{
+					Cleared: []Block{{Row: 2, Col: 1}},
+					Fell: []BlockFall{
+						{Block: Block{Row: 1, Col: 1, Value: -3}, Row: 3},
+						{Block: Block{Row: 0, Col: 1, Value: 5}, Row: 2},
+					},
+					Merged: []Block{{Row: 3, Col: 1, Value: 0}},
+				},
+				{
+					Cleared: []Block{{Row: 3, Col: 1, Value: 0}},
+					Fell: []BlockFall{
+						{Block: Block{Row: 2, Col: 1, Value: 5}, Row: 3},
+					},
+				},
+			},
+		},
+		{
+			name: "dead block falls",
+			blocks: []Block{
+				{Row: 1, Col: 1, Value: 12},
+				{Row: 3, Col: 1, Value: 4},
+			},
+			cleared: []Block{{Row: 2, Col: 1}},
+			want: []string{
+				". . .",
+				". . .",
+				". 12* .",
+				". 4 .",
+			},
+			steps: []ChainStep{{
+				Cleared: []Block{{Row: 2, Col: 1}},
+				Fell: []BlockFall{
+					{Block: Block{Row: 1, Col: 1, Value: 12}, Row: 2},
+				},
+			}},
+		},
+		{
+			name: "live block lands on dead block",
+			blocks: []Block{
+				{Row: 1, Col: 2, Value: 3},
+				{Row: 3, Col: 2, Value: -11},
+			},
+			cleared: []Block{{Row: 2, Col: 2}},
+			want: []string{
+				". . .",
+				". . .",
+				". . 3",
+				". . -11*",
+			},
+			steps: []ChainStep{{
+				Cleared: []Block{{Row: 2, Col: 2}},
+				Fell: []BlockFall{
+					{Block: Block{Row: 1, Col: 2, Value: 3}, Row: 2},
+				},
+			}},
+		},
+		{
+			name: "merge dies",
+			blocks: []Block{
+				{Row: 1, Col: 0, Value: 8},
+				{Row: 3, Col: 0, Value: 5},
+			},
+			cleared: []Block{{Row: 2, Col: 0}},
+			want: []string{
+				". . .",
+				". . .",
+				". . .",
+				"13* . .",
+			},
+			steps: []ChainStep{{
+				Cleared: []Block{{Row: 2, Col: 0}},
+				Fell: []BlockFall{
+					{Block: Block{Row: 1, Col: 0, Value: 8}, Row: 3},
+				},
+				Merged: []Block{{Row: 3, Col: 0, Value: 13}},
+				Died:   []Block{{Row: 3, Col: 0, Value: 13}},
+			}},
+		},
+		{
+			name: "hole in bottom row",
+			blocks: []Block{
+				{Row: 1, Col: 0, Value: 2},
+				{Row: 2, Col: 0, Value: 7},
+			},
+			cleared: []Block{{Row: 3, Col: 0}},
+			want: []string{
+				". . .",
+				". . .",
+				"2 . .",
+				"7 . .",
+			},
+			steps: []ChainStep{{
+				Cleared: []Block{{Row: 3, Col: 0}},
+				Fell: []BlockFall{
+					{Block: Block{Row: 2, Col: 0, Value: 7}, Row: 3},
+					{Block: Block{Row: 1, Col: 0, Value: 2}, Row: 2},
+				},
+			}},
+		},
+		{
+			name:    "nothing cleared",
+			blocks:  []Block{{Row: 3, Col: 0, Value: 2}},
+			cleared: nil,
+			want: []string{
+				". . .",
+				". . .",
+				". . .",
+				"2 . .",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := newTestGame(t, test.blocks...)
+			steps := game.Resolve(test.cleared)
+			if got := boardString(game); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("board =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+			if !reflect.DeepEqual(steps, test.steps) {
+				t.Errorf("steps = %+v, want %+v", steps, test.steps)
+			}
+		})
+	}
+}
+
+// boardString returns a row of the board of game for each row, top first.
+// Empty cells are dots, and dead blocks are marked with a star.
+func boardString(game *GameState) []string {
+	rows := make([]string, game.RowCount())
+	for row := range rows {
+		cells := make([]string, game.ColCount())
+		for col := range cells {
+			switch {
+			case game.IsDead(row, col):
+				cells[col] = fmt.Sprintf("%d*", game.ValueAt(row, col))
+			case game.IsEmpty(row, col):
+				cells[col] = "."
+			default:
+				cells[col] = fmt.Sprint(game.ValueAt(row, col))
+			}
+		}
+		rows[row] = strings.Join(cells, " ")
+	}
+	return rows
+}
@@ -0,0 +1,148 @@
+package numino
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// ModeClassic is the standard numino game mode.
+	ModeClassic = "classic"
+
+	// The number of high scores kept for each grid size and game mode.
+	maxHighScores = 10
+)
+
+// HighScore is an entry in a high score table.
+type HighScore struct {
+	Name     string        `json:"name"`
+	Score    int           `json:"score"`
+	Date     time.Time     `json:"date"`
+	Seed     int64         `json:"seed"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HighScores keeps the best scores for each grid size and game mode.
+type HighScores struct {
+	// Tables maps a key from HighScoreKey to its scores, best first.
+	Tables map[string][]HighScore `json:"tables"`
+}
+
+// HighScoreKey returns the key of the high score table for games on a grid
+// with the given number of rows and columns, in the given mode.
+func HighScoreKey(rows int, cols int, mode string) string {
+	return fmt.Sprintf("%dx%d/%s", cols, rows, mode)
+}
+
+// Top returns the scores in the table with the given key, best first.
+func (h *HighScores) Top(key string) []HighScore {
+	return h.Tables[key]
+}
+
+// Qualifies returns true iff the given score would be added to the table with
+// the given key.
+func (h *HighScores) Qualifies(key string, score int) bool {
+	table := h.Tables[key]
+	return len(table) < maxHighScores || score > table[len(table)-1].Score
+}
+
+// Add adds an entry to the table with the given key.
+//
+// Returns the entry's rank in the table, starting at 0, or -1 if the entry
+// does not qualify for the table.
+func (h *HighScores) Add(key string, entry HighScore) int {
+	if !h.Qualifies(key, entry.Score) {
+		return -1
+	}
+	if h.Tables == nil {
+		h.Tables = make(map[string][]HighScore)
+	}
+
+	table := h.Tables[key]
+	// Earlier entries win ties.
+	rank := sort.Search(len(table), func(i int) bool {
+		return table[i].Score < entry.Score
+	})
+	table = append(table, HighScore{})
+	copy(table[rank+1:], table[rank:])
+	table[rank] = entry
+	if len(table) > maxHighScores {
+		table = table[:maxHighScores]
+	}
+	h.Tables[key] = table
+	return rank
+}
+
+// WriteHighScores writes the given high scores to w.
+func WriteHighScores(w io.Writer, h *HighScores) error {
+	return json.NewEncoder(w).Encode(h)
+}
+
+// ReadHighScores reads high scores from r.
+func ReadHighScores(r io.Reader) (*HighScores, error) {
+	var h HighScores
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, err
+	}
+	for key, table := range h.Tables {
+		sort.SliceStable(table, func(i, j int) bool {
+			return table[i].Score > table[j].Score
+		})
+		if len(table) > maxHighScores {
+			h.Tables[key] = table[:maxHighScores]
+		}
+	}
+	return &h, nil
+}
+
+// HighScoresPath returns the path of the file where high scores are saved.
+func HighScoresPath() (string, error) {
+	return configPath("highscores.json")
+}
+
+// LoadHighScores reads the high scores at HighScoresPath.
+//
+// If the file is missing, empty high scores are returned. If the file cannot
+// be read, the error is logged and empty high scores are returned. A corrupt
+// file is moved aside so that it is not overwritten by the next save.
+func LoadHighScores() *HighScores {
+	path, err := HighScoresPath()
+	if err != nil {
+		log.Println("failed to find high scores:", err)
+		return &HighScores{}
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &HighScores{}
+	}
+	if err != nil {
+		log.Println("failed to load high scores:", err)
+		return &HighScores{}
+	}
+	h, err := ReadHighScores(f)
+	f.Close()
+	if err != nil {
+		log.Println("failed to load high scores:", err)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			log.Println(err)
+		}
+		return &HighScores{}
+	}
+	return h
+}
+
+// StoreHighScores writes the given high scores to HighScoresPath.
+func StoreHighScores(h *HighScores) error {
+	path, err := HighScoresPath()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteHighScores(w, h)
+	})
+}
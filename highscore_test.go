@@ -0,0 +1,147 @@
+package numino
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempConfigDir points numino's config dir at a new temporary directory
+// for the rest of the test.
+func useTempConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+}
+
+// scores returns the scores in the table with the given key, best first.
+func scores(h *HighScores, key string) []int {
+	var scores []int
+	for _, entry := range h.Top(key) {
+		scores = append(scores, entry.Score)
+	}
+	return scores
+}
+
+func TestHighScoreKey(t *testing.T) {
+	if got, want := HighScoreKey(9, 6, ModeClassic), "6x9/classic"; got != want {
+		t.Errorf("HighScoreKey() = %q, want %q", got, want)
+	}
+}
+
+func TestHighScoresKeepsTop(t *testing.T) {
+	key := HighScoreKey(9, 6, ModeClassic)
+	var h HighScores
+	for score := 1; score <= maxHighScores+5; score++ {
+		h.Add(key, HighScore{Score: score})
+	}
+
+	want := []int{15, 14, 13, 12, 11, 10, 9, 8, 7, 6}
+	if got := scores(&h, key); !reflect.DeepEqual(got, want) {
+		t.Errorf("scores = %v, want %v", got, want)
+	}
+	if h.Qualifies(key, 5) {
+		t.Error("Qualifies(5) = true for a full table, want false")
+	}
+	if rank := h.Add(key, HighScore{Score: 100}); rank != 0 {
+		t.Errorf("Add(100) = %d, want 0", rank)
+	}
+	if got := len(h.Top(key)); got != maxHighScores {
+		t.Errorf("len(Top()) = %d, want %d", got, maxHighScores)
+	}
+}
+
+func TestHighScoresPerKey(t *testing.T) {
+	small := HighScoreKey(4, 4, ModeClassic)
+	large := HighScoreKey(9, 6, ModeClassic)
+	var h HighScores
+	h.Add(small, HighScore{Score: 10})
+	h.Add(large, HighScore{Score: 20})
+	h.Add(small, HighScore{Score: 30})
+
+	if got, want := scores(&h, small), []int{30, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("%s scores = %v, want %v", small, got, want)
+	}
+	if got, want := scores(&h, large), []int{20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("%s scores = %v, want %v", large, got, want)
+	}
+	if got := scores(&h, HighScoreKey(9, 6, "other")); got != nil {
+		t.Errorf("scores of other mode = %v, want none", got)
+	}
+}
+
+func TestHighScoresTies(t *testing.T) {
+	key := HighScoreKey(9, 6, ModeClassic)
+	var h HighScores
+	for i := 0; i < maxHighScores; i++ {
+		h.Add(key, HighScore{Name: "first", Score: 50})
+	}
+
+	// A score that ties the last entry of a full table does not qualify.
+	if h.Qualifies(key, 50) {
+		t.Error("Qualifies() of a tie at the cutoff = true, want false")
+	}
+	if rank := h.Add(key, HighScore{Name: "second", Score: 50}); rank != -1 {
+		t.Errorf("Add() of a tie at the cutoff = %d, want -1", rank)
+	}
+
+	// Ties above the cutoff rank below the earlier entries.
+	h.Tables[key] = h.Tables[key][:3]
+	if rank := h.Add(key, HighScore{Name: "second", Score: 50}); rank != 3 {
+		t.Errorf("Add() of a tie = %d, want 3", rank)
+	}
+}
+
+func TestLoadHighScores(t *testing.T) {
+	useTempConfigDir(t)
+	key := HighScoreKey(9, 6, ModeClassic)
+
+	// Missing high scores are empty.
+	if got := LoadHighScores(); len(got.Tables) != 0 {
+		t.Errorf("LoadHighScores() with no file = %+v, want empty", got)
+	}
+
+	h := &HighScores{}
+	h.Add(key, HighScore{Name: "a", Score: 3})
+	h.Add(key, HighScore{Name: "b", Score: 7})
+	if err := StoreHighScores(h); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := scores(LoadHighScores(), key), []int{7, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded scores = %v, want %v", got, want)
+	}
+}
+
+func TestLoadHighScoresCorrupt(t *testing.T) {
+	useTempConfigDir(t)
+	path, err := HighScoresPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	const corrupt = `{"tables": {"6x9/classic": [{"score": `
+	if err := ioutil.WriteFile(path, []byte(corrupt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := LoadHighScores(); len(got.Tables) != 0 {
+		t.Errorf("LoadHighScores() of corrupt file = %+v, want empty", got)
+	}
+	// The corrupt file is moved aside, so that the next save does not
+	// overwrite it.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("corrupt file is still at %s", path)
+	}
+	data, err := ioutil.ReadFile(path + ".corrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != corrupt {
+		t.Errorf("%s.corrupt = %q, want %q", path, data, corrupt)
+	}
+}
@@ -0,0 +1,72 @@
+package numino
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ImageCanvas is a Canvas that draws to an in-memory image, without a window.
+type ImageCanvas struct {
+	img *image.RGBA
+}
+
+// NewImageCanvas returns a canvas of the given size in pixels, filled with
+// ColorBg.
+func NewImageCanvas(width int, height int) *ImageCanvas {
+	c := &ImageCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	c.Clear(ColorBg)
+	return c
+}
+
+// NewGridImageCanvas returns a canvas the size of grid, filled with ColorBg.
+func NewGridImageCanvas(grid *Grid) *ImageCanvas {
+	return NewImageCanvas(int(grid.PixelWidth()), int(grid.PixelHeight()))
+}
+
+// Image returns the image this canvas draws to.
+func (c *ImageCanvas) Image() *image.RGBA {
+	return c.img
+}
+
+// Clear fills this canvas with the given color.
+func (c *ImageCanvas) Clear(col color.RGBA) {
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+// Rect implements the Canvas interface.
+func (c *ImageCanvas) Rect(x, y, w, h float64, col color.RGBA) {
+	// Images have their origin at the top left.
+	r := image.Rect(round(x), c.flip(y+h), round(x+w), c.flip(y))
+	draw.Draw(c.img, r, image.NewUniform(col), image.Point{}, draw.Over)
+}
+
+// Text implements the Canvas interface.
+func (c *ImageCanvas) Text(x, y float64, msg string, col color.RGBA) {
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(col),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(round(x), c.flip(y)),
+	}
+	d.DrawString(msg)
+}
+
+// TextWidth implements the Canvas interface.
+func (c *ImageCanvas) TextWidth(msg string) float64 {
+	return float64(font.MeasureString(basicfont.Face7x13, msg).Round())
+}
+
+// flip converts a y position on the canvas to a y position in the image.
+func (c *ImageCanvas) flip(y float64) int {
+	return c.img.Bounds().Dy() - round(y)
+}
+
+func round(x float64) int {
+	return int(math.Floor(x + 0.5))
+}
@@ -0,0 +1,175 @@
+package numino
+
+import "github.com/faiface/pixel/pixelgl"
+
+// The keys and gamepad buttons that move through menus.
+var (
+	menuKeys = map[Action][]pixelgl.Button{
+		ActionMenuUp:     {pixelgl.KeyUp, pixelgl.KeyLeft},
+		ActionMenuDown:   {pixelgl.KeyDown, pixelgl.KeyRight, pixelgl.KeyTab},
+		ActionMenuSelect: {pixelgl.KeyEnter, pixelgl.KeySpace},
+		ActionMenuBack:   {pixelgl.KeyEscape},
+	}
+	menuButtons = map[Action][]pixelgl.GamepadButton{
+		ActionMenuUp:     {pixelgl.ButtonDpadUp},
+		ActionMenuDown:   {pixelgl.ButtonDpadDown},
+		ActionMenuSelect: {pixelgl.ButtonA},
+		ActionMenuBack:   {pixelgl.ButtonB},
+	}
+)
+
+// The number of joysticks that can be connected to a window.
+const joystickCount = int(pixelgl.JoystickLast) + 1
+
+// Controls turns the keyboard and gamepad input of a window into actions, so
+// that views handle what the player wants to do rather than the keys they
+// pressed.
+//
+// Update must be called once per frame, before any actions are checked.
+type Controls struct {
+	win      WindowInput
+	bindings *Bindings
+
+	// The direction the left stick of each joystick is pushed in along each
+	// axis, as -1, 0 or 1, and whether it was pushed there since the last
+	// update.
+	stick       [joystickCount]stickState
+	stickMoved  [joystickCount]stickState
+	initialized bool
+}
+
+// stickState is the direction a stick is pushed in, with -1 left or up, 0 in
+// the deadzone and 1 right or down.
+type stickState struct {
+	X, Y int
+}
+
+// NewControls returns Controls for win with the given bindings.
+func NewControls(win WindowInput, bindings *Bindings) *Controls {
+	return &Controls{win: win, bindings: bindings}
+}
+
+// Update reads the gamepad sticks for this frame.
+func (c *Controls) Update() {
+	deadzone := c.bindings.Gamepad.Deadzone
+	for i := range c.stick {
+		js := pixelgl.Joystick(i)
+		var now stickState
+		if c.win.JoystickPresent(js) {
+			now.X = axisDirection(c.win.JoystickAxis(js, pixelgl.AxisLeftX), deadzone)
+			now.Y = axisDirection(c.win.JoystickAxis(js, pixelgl.AxisLeftY), deadzone)
+		}
+		c.stickMoved[i] = stickState{}
+		// A stick that is already pushed when the controls are created does
+		// not count as moved, like a key that is already held.
+		if c.initialized && now.X != c.stick[i].X {
+			c.stickMoved[i].X = now.X
+		}
+		if c.initialized && now.Y != c.stick[i].Y {
+			c.stickMoved[i].Y = now.Y
+		}
+		c.stick[i] = now
+	}
+	c.initialized = true
+}
+
+// axisDirection returns the direction of an axis at the given position, or 0
+// if the position is within deadzone of the center.
+func axisDirection(position float64, deadzone float64) int {
+	switch {
+	case position > deadzone:
+		return 1
+	case position < -deadzone:
+		return -1
+	}
+	return 0
+}
+
+// JustPressed returns true iff the player did the given action since the last
+// update: by pressing a key or gamepad button bound to it, or by pushing a
+// stick in its direction.
+func (c *Controls) JustPressed(action Action) bool {
+	return c.check(action, c.win.JustPressed, c.win.JoystickJustPressed, c.stickMoved)
+}
+
+// Pressed returns true iff the player is holding down the given action: by
+// holding a key or gamepad button bound to it, or by holding a stick in its
+// direction.
+func (c *Controls) Pressed(action Action) bool {
+	return c.check(action, c.win.Pressed, c.win.JoystickPressed, c.stick)
+}
+
+// check returns true iff any key, gamepad button or stick bound to action is
+// down, according to the given functions and stick states.
+func (c *Controls) check(
+	action Action,
+	keyDown func(pixelgl.Button) bool,
+	buttonDown func(pixelgl.Joystick, pixelgl.GamepadButton) bool,
+	sticks [joystickCount]stickState,
+) bool {
+	keys := append([]pixelgl.Button(nil), menuKeys[action]...)
+	for _, key := range c.bindings.Keys[action] {
+		keys = append(keys, pixelgl.Button(key))
+	}
+	for _, key := range keys {
+		if keyDown(key) {
+			return true
+		}
+	}
+
+	buttons := append([]pixelgl.GamepadButton(nil), menuButtons[action]...)
+	for _, button := range c.bindings.Gamepad.Buttons[action] {
+		buttons = append(buttons, pixelgl.GamepadButton(button))
+	}
+	for i := range sticks {
+		js := pixelgl.Joystick(i)
+		if !c.win.JoystickPresent(js) {
+			continue
+		}
+		for _, button := range buttons {
+			if buttonDown(js, button) {
+				return true
+			}
+		}
+		if stickPushed(sticks[i], action) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeldShift returns the shift input that the player is holding down, or
+// NoInput if they are holding neither or both shift actions.
+func (c *Controls) HeldShift() Input {
+	left, right := c.Pressed(ActionShiftLeft), c.Pressed(ActionShiftRight)
+	switch {
+	case left && !right:
+		return ShiftLeftInput
+	case right && !left:
+		return ShiftRightInput
+	}
+	return NoInput
+}
+
+// stickPushed returns true iff a stick in the given state is pushed in the
+// direction of action.
+func stickPushed(stick stickState, action Action) bool {
+	switch action {
+	case ActionShiftLeft:
+		return stick.X < 0
+	case ActionShiftRight:
+		return stick.X > 0
+	case ActionMenuUp:
+		return stick.Y < 0
+	case ActionMenuDown:
+		return stick.Y > 0
+	}
+	return false
+}
+
+// UpdateSelection returns the menu selection after applying the menu actions
+// done by the player. The selection wraps around the given number of options.
+func (c *Controls) UpdateSelection(selection int, count int) int {
+	return moveSelection(selection, count,
+		c.JustPressed(ActionMenuDown), c.JustPressed(ActionMenuUp))
+}
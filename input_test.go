@@ -0,0 +1,195 @@
+package numino
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// fakeInput is the input of a window with at most one joystick connected.
+type fakeInput struct {
+	justPressed map[pixelgl.Button]bool
+	pressed     map[pixelgl.Button]bool
+	// joystick is true if the first joystick is connected, and stick is the
+	// position of its left stick.
+	joystick bool
+	stick    pixel.Vec
+}
+
+func (in *fakeInput) JustPressed(button pixelgl.Button) bool {
+	return in.justPressed[button]
+}
+
+func (in *fakeInput) Pressed(button pixelgl.Button) bool {
+	return in.pressed[button]
+}
+
+func (in *fakeInput) Typed() string {
+	return ""
+}
+
+func (in *fakeInput) JoystickPresent(js pixelgl.Joystick) bool {
+	return in.joystick && js == pixelgl.Joystick1
+}
+
+func (in *fakeInput) JoystickJustPressed(pixelgl.Joystick, pixelgl.GamepadButton) bool {
+	return false
+}
+
+func (in *fakeInput) JoystickPressed(pixelgl.Joystick, pixelgl.GamepadButton) bool {
+	return false
+}
+
+func (in *fakeInput) JoystickAxis(js pixelgl.Joystick, axis pixelgl.GamepadAxis) float64 {
+	if !in.JoystickPresent(js) {
+		return 0
+	}
+	switch axis {
+	case pixelgl.AxisLeftX:
+		return in.stick.X
+	case pixelgl.AxisLeftY:
+		return in.stick.Y
+	}
+	return 0
+}
+
+func TestAxisDirection(t *testing.T) {
+	tests := []struct {
+		position float64
+		deadzone float64
+		want     int
+	}{
+		{position: 0, deadzone: 0.5, want: 0},
+		{position: 0.3, deadzone: 0.5, want: 0},
+		{position: -0.3, deadzone: 0.5, want: 0},
+		{position: 0.5, deadzone: 0.5, want: 0},
+		{position: -0.5, deadzone: 0.5, want: 0},
+		{position: 0.6, deadzone: 0.5, want: 1},
+		{position: -0.6, deadzone: 0.5, want: -1},
+		{position: 1, deadzone: 0.5, want: 1},
+		{position: -1, deadzone: 0.5, want: -1},
+		{position: 0, deadzone: 0, want: 0},
+		{position: 0.01, deadzone: 0, want: 1},
+		{position: -0.01, deadzone: 0, want: -1},
+	}
+
+	for _, test := range tests {
+		if got := axisDirection(test.position, test.deadzone); got != test.want {
+			t.Errorf("axisDirection(%v, %v) = %d, want %d", test.position, test.deadzone, got, test.want)
+		}
+	}
+}
+
+func TestControlsStick(t *testing.T) {
+	// frame is the position of the stick in one frame, and the actions the
+	// controls should report after updating.
+	type frame struct {
+		stick           pixel.Vec
+		wantJustPressed bool
+		wantPressed     bool
+		wantShift       Input
+	}
+	down := pixel.Vec{Y: 0.8}
+
+	tests := []struct {
+		name     string
+		joystick bool
+		frames   []frame
+	}{
+		{
+			name:     "pushed down",
+			joystick: true,
+			frames: []frame{
+				{},
+				{stick: down, wantJustPressed: true, wantPressed: true},
+				{stick: pixel.Vec{Y: 1}, wantPressed: true},
+				{stick: pixel.Vec{Y: 0.2}},
+				{stick: down, wantJustPressed: true, wantPressed: true},
+			},
+		},
+		{
+			name:     "pushed before the controls were created",
+			joystick: true,
+			frames: []frame{
+				{stick: down, wantPressed: true},
+				{stick: down, wantPressed: true},
+				{},
+				{stick: down, wantJustPressed: true, wantPressed: true},
+			},
+		},
+		{
+			name:     "within the dead zone",
+			joystick: true,
+			frames: []frame{
+				{},
+				{stick: pixel.Vec{X: -0.4, Y: 0.4}},
+				{stick: pixel.Vec{X: 0.5, Y: 0.5}},
+			},
+		},
+		{
+			name:     "pushed sideways",
+			joystick: true,
+			frames: []frame{
+				{},
+				{stick: pixel.Vec{X: -0.8}, wantShift: ShiftLeftInput},
+				{stick: pixel.Vec{X: 0.8}, wantShift: ShiftRightInput},
+				{stick: pixel.Vec{X: 0.8, Y: 0.8}, wantJustPressed: true, wantPressed: true, wantShift: ShiftRightInput},
+			},
+		},
+		{
+			name: "no joystick",
+			frames: []frame{
+				{},
+				{stick: down},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := &fakeInput{joystick: test.joystick}
+			bindings := DefaultBindings()
+			bindings.Gamepad.Deadzone = 0.5
+			controls := NewControls(input, bindings)
+			for i, frame := range test.frames {
+				input.stick = frame.stick
+				controls.Update()
+				if got := controls.JustPressed(ActionMenuDown); got != frame.wantJustPressed {
+					t.Errorf("frame %d: JustPressed(ActionMenuDown) = %v, want %v", i, got, frame.wantJustPressed)
+				}
+				if got := controls.Pressed(ActionMenuDown); got != frame.wantPressed {
+					t.Errorf("frame %d: Pressed(ActionMenuDown) = %v, want %v", i, got, frame.wantPressed)
+				}
+				if got := controls.HeldShift(); got != frame.wantShift {
+					t.Errorf("frame %d: HeldShift() = %v, want %v", i, got, frame.wantShift)
+				}
+			}
+		})
+	}
+}
+
+func TestControlsKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    pixelgl.Button
+		action Action
+	}{
+		{name: "menu key", key: pixelgl.KeyDown, action: ActionMenuDown},
+		{name: "escape backs out", key: pixelgl.KeyEscape, action: ActionMenuBack},
+		{name: "bound key", key: pixelgl.KeyQ, action: ActionQuit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := &fakeInput{justPressed: map[pixelgl.Button]bool{test.key: true}}
+			controls := NewControls(input, DefaultBindings())
+			controls.Update()
+			for _, action := range []Action{ActionMenuDown, ActionMenuBack, ActionQuit} {
+				if got, want := controls.JustPressed(action), action == test.action; got != want {
+					t.Errorf("JustPressed(%v) = %v, want %v", action, got, want)
+				}
+			}
+		})
+	}
+}
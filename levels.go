@@ -0,0 +1,121 @@
+package numino
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// Level describes how blocks fall and spawn at a level of the game.
+type Level struct {
+	// TicksPerStep is the number of ticks it takes blocks to fall one row.
+	TicksPerStep float64 `json:"ticksPerStep"`
+	// SpawnChance is the probability that a block spawns in each column of a
+	// new wave.
+	SpawnChance float64 `json:"spawnChance"`
+	// MinValue and MaxValue are the range of values of new blocks.
+	MinValue int `json:"minValue"`
+	MaxValue int `json:"maxValue"`
+}
+
+// validate returns an error if blocks cannot spawn at this level, given the
+// largest absolute value of a live block.
+func (l Level) validate(maxLiveValue int) error {
+	switch {
+	case l.TicksPerStep <= 0:
+		return fmt.Errorf("ticks per step must be positive, got %v", l.TicksPerStep)
+	case l.SpawnChance <= 0 || l.SpawnChance > 1:
+		return fmt.Errorf("spawn chance must be in (0, 1], got %v", l.SpawnChance)
+	case l.MinValue > l.MaxValue:
+		return fmt.Errorf("min value %d is greater than max value %d", l.MinValue, l.MaxValue)
+	case l.MinValue == 0 && l.MaxValue == 0:
+		return errors.New("value range must contain a value other than zero")
+	case l.MinValue < -maxLiveValue || l.MaxValue > maxLiveValue:
+		return fmt.Errorf("values must be live, got [%d, %d] with max live value %d",
+			l.MinValue, l.MaxValue, maxLiveValue)
+	}
+	return nil
+}
+
+// LevelCurve decides when a game levels up, and how each level plays.
+//
+// The game levels up when either enough blocks have landed or enough points
+// have been scored since the start of the game, whichever comes first.
+type LevelCurve struct {
+	// BlocksPerLevel is the number of blocks that must land to advance a
+	// level. Zero means that landing blocks never levels up.
+	BlocksPerLevel int `json:"blocksPerLevel"`
+	// PointsPerLevel is the number of points that must be scored to advance
+	// a level. Zero means that points never level up.
+	PointsPerLevel int `json:"pointsPerLevel"`
+	// Levels are the levels after the first, in order. The first level plays
+	// with the rules' starting ticks per step, spawn chance and value range.
+	//
+	// Levels past the end of the table play like the last level, except that
+	// blocks fall faster by the rules' speedup factor at each level.
+	Levels []Level `json:"levels"`
+}
+
+// DefaultLevelCurve returns the level curve of a classic game of numino.
+func DefaultLevelCurve() LevelCurve {
+	return LevelCurve{
+		BlocksPerLevel: 20,
+		Levels: []Level{
+			{TicksPerStep: 108, SpawnChance: 0.2, MinValue: -3, MaxValue: 6},
+			{TicksPerStep: 97, SpawnChance: 0.22, MinValue: -4, MaxValue: 6},
+			{TicksPerStep: 87, SpawnChance: 0.24, MinValue: -4, MaxValue: 7},
+			{TicksPerStep: 78, SpawnChance: 0.26, MinValue: -5, MaxValue: 7},
+			{TicksPerStep: 70, SpawnChance: 0.28, MinValue: -5, MaxValue: 8},
+			{TicksPerStep: 63, SpawnChance: 0.3, MinValue: -6, MaxValue: 8},
+			{TicksPerStep: 57, SpawnChance: 0.3, MinValue: -6, MaxValue: 9},
+		},
+	}
+}
+
+// RegisterFlags defines a flag for when this curve levels up in fs. Each
+// flag's default value is the field's current value. The table of levels can
+// only be set in a rules file.
+func (c *LevelCurve) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.BlocksPerLevel, "blocks-per-level", c.BlocksPerLevel, "blocks that must land to advance a level, or 0 to never level up by landing")
+	fs.IntVar(&c.PointsPerLevel, "points-per-level", c.PointsPerLevel, "points that must be scored to advance a level, or 0 to never level up by scoring")
+}
+
+// Reached returns the level reached once the given number of blocks have
+// landed and the given number of points have been scored. Levels start at 1.
+func (c LevelCurve) Reached(blocksLanded int, points int) int {
+	level := 1
+	if c.BlocksPerLevel > 0 && 1+blocksLanded/c.BlocksPerLevel > level {
+		level = 1 + blocksLanded/c.BlocksPerLevel
+	}
+	if c.PointsPerLevel > 0 && points > 0 && 1+points/c.PointsPerLevel > level {
+		level = 1 + points/c.PointsPerLevel
+	}
+	return level
+}
+
+// Level returns how the given level plays with these rules. Levels start at
+// 1.
+func (r Rules) Level(n int) Level {
+	level := Level{
+		TicksPerStep: r.StartingTicksPerStep,
+		SpawnChance:  r.SpawnChance,
+		MinValue:     r.MinValue,
+		MaxValue:     r.MaxValue,
+	}
+	if n <= 1 {
+		return level
+	}
+
+	table := r.Levels.Levels
+	if len(table) > 0 {
+		i := n - 2
+		if i >= len(table) {
+			i = len(table) - 1
+		}
+		level = table[i]
+	}
+	for i := len(table) + 2; i <= n; i++ {
+		level.TicksPerStep *= r.SpeedupFactor
+	}
+	return level
+}
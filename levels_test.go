@@ -0,0 +1,136 @@
+package numino
+
+import "testing"
+
+func TestLevelCurveReached(t *testing.T) {
+	tests := []struct {
+		name         string
+		curve        LevelCurve
+		blocksLanded int
+		points       int
+		want         int
+	}{
+		{
+			name:  "start of game",
+			curve: LevelCurve{BlocksPerLevel: 20, PointsPerLevel: 100},
+			want:  1,
+		},
+		{
+			name:         "by blocks",
+			curve:        LevelCurve{BlocksPerLevel: 20, PointsPerLevel: 100},
+			blocksLanded: 45,
+			points:       50,
+			want:         3,
+		},
+		{
+			name:         "by points",
+			curve:        LevelCurve{BlocksPerLevel: 20, PointsPerLevel: 100},
+			blocksLanded: 5,
+			points:       420,
+			want:         5,
+		},
+		{
+			name:         "negative points",
+			curve:        LevelCurve{PointsPerLevel: 100},
+			blocksLanded: 5,
+			points:       -250,
+			want:         1,
+		},
+		{
+			name:         "never levels up",
+			blocksLanded: 1000,
+			points:       1000,
+			want:         1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.curve.Reached(test.blocksLanded, test.points); got != test.want {
+				t.Errorf("Reached(%d, %d) = %d, want %d", test.blocksLanded, test.points, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRulesLevel(t *testing.T) {
+	rules := DefaultRules()
+	rules.StartingTicksPerStep = 100
+	rules.SpawnChance = 0.2
+	rules.MinValue = -3
+	rules.MaxValue = 6
+	rules.SpeedupFactor = 0.5
+	rules.Levels.Levels = []Level{
+		{TicksPerStep: 90, SpawnChance: 0.25, MinValue: -4, MaxValue: 6},
+		{TicksPerStep: 80, SpawnChance: 0.3, MinValue: -5, MaxValue: 7},
+	}
+	first := Level{TicksPerStep: 100, SpawnChance: 0.2, MinValue: -3, MaxValue: 6}
+	last := rules.Levels.Levels[1]
+
+	noTable := rules
+	noTable.Levels.Levels = nil
+
+	tests := []struct {
+		name  string
+		rules Rules
+		level int
+		want  Level
+	}{
+		{
+			name:  "level 1",
+			rules: rules,
+			level: 1,
+			want:  first,
+		},
+		{
+			name:  "before level 1",
+			rules: rules,
+			level: 0,
+			want:  first,
+		},
+		{
+			name:  "first table entry",
+			rules: rules,
+			level: 2,
+			want:  rules.Levels.Levels[0],
+		},
+		{
+			name:  "last table entry",
+			rules: rules,
+			level: 3,
+			want:  last,
+		},
+		{
+			name:  "one past the table",
+			rules: rules,
+			level: 4,
+			want:  Level{TicksPerStep: 40, SpawnChance: last.SpawnChance, MinValue: last.MinValue, MaxValue: last.MaxValue},
+		},
+		{
+			name:  "two past the table",
+			rules: rules,
+			level: 5,
+			want:  Level{TicksPerStep: 20, SpawnChance: last.SpawnChance, MinValue: last.MinValue, MaxValue: last.MaxValue},
+		},
+		{
+			name:  "empty table, level 1",
+			rules: noTable,
+			level: 1,
+			want:  first,
+		},
+		{
+			name:  "empty table, level 3",
+			rules: noTable,
+			level: 3,
+			want:  Level{TicksPerStep: 25, SpawnChance: 0.2, MinValue: -3, MaxValue: 6},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.rules.Level(test.level); got != test.want {
+				t.Errorf("Level(%d) = %+v, want %+v", test.level, got, test.want)
+			}
+		})
+	}
+}
@@ -9,20 +9,25 @@ import (
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
-	"github.com/faiface/pixel/pixelgl"
 	"github.com/faiface/pixel/text"
 )
 
 // Renderer is an interface for objects that draw to a window.
 type Renderer interface {
-	Render(*pixelgl.Window)
+	Render(RenderTarget)
+}
+
+// RenderTarget is what a Renderer draws to, such as a window.
+type RenderTarget interface {
+	pixel.Target
+	SetColorMask(color.Color)
 }
 
 // MultiRenderer executes multiple Renderers at once.
 type MultiRenderer []Renderer
 
 // Render implements the Renderer interface
-func (drawer MultiRenderer) Render(win *pixelgl.Window) {
+func (drawer MultiRenderer) Render(win RenderTarget) {
 	for _, d := range drawer {
 		d.Render(win)
 	}
@@ -38,86 +43,71 @@ func NewImageRenderer(img *imdraw.IMDraw) *ImageRenderer {
 }
 
 // Render implements the Renderer interface
-func (r ImageRenderer) Render(win *pixelgl.Window) {
+func (r ImageRenderer) Render(win RenderTarget) {
 	r.img.Draw(win)
 }
 
 // TextRenderer draws text to a window.
 type TextRenderer struct {
-	text *text.Text
+	text  *text.Text
+	color color.RGBA
 }
 
-func NewTextRenderer(text *text.Text) *TextRenderer {
-	return &TextRenderer{text}
+func NewTextRenderer(text *text.Text, color color.RGBA) *TextRenderer {
+	return &TextRenderer{text, color}
 }
 
 // Render implements the Renderer interface
-func (r TextRenderer) Render(win *pixelgl.Window) {
-	r.text.DrawColorMask(win, pixel.IM, colornames.Black)
+func (r TextRenderer) Render(win RenderTarget) {
+	r.text.DrawColorMask(win, pixel.IM, r.color)
 	win.SetColorMask(colornames.Aliceblue)
 }
 
+// ImageBuffer is a Canvas that collects shapes and text to draw to a window.
+//
+// Text is drawn on top of shapes.
 type ImageBuffer struct {
 	img   *imdraw.IMDraw
-	txt   []*text.Text
+	txt   []*TextRenderer
 	atlas *text.Atlas
 }
 
 func NewImageBuffer() *ImageBuffer {
 	return &ImageBuffer{
 		img:   imdraw.New(nil),
-		txt:   []*text.Text{},
+		txt:   []*TextRenderer{},
 		atlas: text.NewAtlas(basicfont.Face7x13, text.ASCII),
 	}
 }
 
 func (buf ImageBuffer) Renderer() Renderer {
-	var txtRs []Renderer
+	rs := []Renderer{NewImageRenderer(buf.img)}
 	for i := range buf.txt {
-		txtRs = append(txtRs, NewTextRenderer(buf.txt[i]))
+		rs = append(rs, buf.txt[i])
 	}
-
-	rs := []Renderer{NewImageRenderer(buf.img)}
-	return MultiRenderer(append(rs, txtRs...))
+	return MultiRenderer(rs)
 }
 
-func (buf *ImageBuffer) Color(c color.RGBA) {
+// Rect implements the Canvas interface.
+func (buf *ImageBuffer) Rect(x, y, w, h float64, c color.RGBA) {
 	buf.img.Color = c
-}
-
-func (buf *ImageBuffer) Vertex(x float64, y float64) {
-	buf.img.Push(pixel.V(x, y))
-}
-
-func (buf *ImageBuffer) Polygon() {
+	buf.img.Push(
+		pixel.V(x, y),
+		pixel.V(x+w, y),
+		pixel.V(x+w, y+h),
+		pixel.V(x, y+h),
+	)
 	buf.img.Polygon(0)
 }
 
-func (buf *ImageBuffer) Text(row float64, col float64, msg string) {
-	txt := text.New(pixel.V(col, row), buf.atlas)
-	fmt.Fprintf(txt, msg)
-	buf.txt = append(buf.txt, txt)
+// Text implements the Canvas interface.
+func (buf *ImageBuffer) Text(x, y float64, msg string, c color.RGBA) {
+	txt := text.New(pixel.V(x, y), buf.atlas)
+	fmt.Fprint(txt, msg)
+	buf.txt = append(buf.txt, NewTextRenderer(txt, c))
 }
 
-type ScoreRenderer struct {
-	txt *text.Text
-}
-
-func NewScoreRenderer(x float64, y float64) *ScoreRenderer {
-	return &ScoreRenderer{
-		txt: text.New(
-			pixel.V(x, y),
-			text.NewAtlas(basicfont.Face7x13, text.ASCII),
-		),
-	}
-}
-
-func (s *ScoreRenderer) SetScore(score float64) {
-	s.txt.Clear()
-	fmt.Fprintf(s.txt, "Score: %v", score)
-}
-
-func (s *ScoreRenderer) Render(win *pixelgl.Window) {
-	s.txt.DrawColorMask(win, pixel.IM, colornames.Black)
-	win.SetColorMask(colornames.Aliceblue)
+// TextWidth implements the Canvas interface.
+func (buf *ImageBuffer) TextWidth(msg string) float64 {
+	return text.New(pixel.ZV, buf.atlas).BoundsOf(msg).W()
 }
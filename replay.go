@@ -0,0 +1,274 @@
+package numino
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The current version of the replay file format. Replays from older
+	// versions play out differently, because blocks did not fall into
+	// cleared cells, blocks sped up over time instead of by level, and new
+	// and held waves could fall before a full step had passed.
+	replayVersion = 5
+	// The file extension used for replay files.
+	replayExt = ".replay"
+	// The layout of the time that replay files are named by.
+	replayTimeLayout = "2006-01-02-150405"
+)
+
+// Replay is a recording of a game.
+//
+// A game is reproduced by creating an Engine with the replay's rules and seed,
+// and stepping it with the recorded inputs until Ticks have elapsed.
+type Replay struct {
+	Version int   `json:"version"`
+	Seed    int64 `json:"seed"`
+	Rules   Rules `json:"rules"`
+	// Ticks is the number of ticks that elapsed in the recorded game.
+	Ticks  int           `json:"ticks"`
+	Events []ReplayEvent `json:"events"`
+}
+
+// ReplayEvent is an input that was applied during a tick of a recorded game.
+type ReplayEvent struct {
+	Tick  int
+	Input Input
+}
+
+// MarshalJSON encodes this event as a compact [tick, input] pair.
+func (e ReplayEvent) MarshalJSON() ([]byte, error) {
+	input, err := e.Input.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal([]interface{}{e.Tick, string(input)})
+}
+
+// UnmarshalJSON decodes an event from a [tick, input] pair.
+func (e *ReplayEvent) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid replay event: %s", data)
+	}
+	if err := json.Unmarshal(pair[0], &e.Tick); err != nil {
+		return err
+	}
+	var input string
+	if err := json.Unmarshal(pair[1], &input); err != nil {
+		return err
+	}
+	return e.Input.UnmarshalText([]byte(input))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (input Input) MarshalText() ([]byte, error) {
+	switch input {
+	case NoInput:
+		return []byte("-"), nil
+	case ShiftLeftInput:
+		return []byte("L"), nil
+	case ShiftRightInput:
+		return []byte("R"), nil
+	case SlamInput:
+		return []byte("S"), nil
+	case HoldInput:
+		return []byte("H"), nil
+	}
+	return nil, fmt.Errorf("invalid input: %d", input)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (input *Input) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "-":
+		*input = NoInput
+	case "L":
+		*input = ShiftLeftInput
+	case "R":
+		*input = ShiftRightInput
+	case "S":
+		*input = SlamInput
+	case "H":
+		*input = HoldInput
+	default:
+		return fmt.Errorf("invalid input: %q", text)
+	}
+	return nil
+}
+
+// WriteReplay writes the given replay to w.
+func WriteReplay(w io.Writer, replay *Replay) error {
+	return json.NewEncoder(w).Encode(replay)
+}
+
+// ReadReplay reads a replay from r.
+func ReadReplay(r io.Reader) (*Replay, error) {
+	// Rules missing from the file, such as scoring, have their default values.
+	replay := Replay{Rules: DefaultRules()}
+	if err := json.NewDecoder(r).Decode(&replay); err != nil {
+		return nil, err
+	}
+	if replay.Version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version: %d", replay.Version)
+	}
+	if err := replay.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid replay rules: %v", err)
+	}
+	return &replay, nil
+}
+
+// ReplayDir returns the directory where replays are saved.
+func ReplayDir() (string, error) {
+	return configPath("replays")
+}
+
+// SaveReplay saves the given replay to a new file in ReplayDir.
+//
+// Replays are named by the time they are saved. A replay saved in the same
+// second as another is given a numbered suffix, so that it never replaces it.
+// The path of the new file is returned.
+func SaveReplay(replay *Replay) (string, error) {
+	dir, err := ReplayDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	f, err := createReplayFile(dir, time.Now().Format(replayTimeLayout))
+	if err != nil {
+		return "", err
+	}
+	if err := WriteReplay(f, replay); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// createReplayFile creates a new replay file in dir with the given name, or
+// with the name and the first free numbered suffix if a file with that name
+// already exists.
+func createReplayFile(dir, name string) (*os.File, error) {
+	path := filepath.Join(dir, name+replayExt)
+	for n := 2; ; n++ {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, n, replayExt))
+	}
+}
+
+// LoadReplay loads the replay in the file at path.
+func LoadReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
+
+// ListReplays returns the paths of all replays in ReplayDir, newest first.
+func ListReplays() ([]string, error) {
+	dir, err := ReplayDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+replayExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		iTime, iSuffix := replayOrder(paths[i])
+		jTime, jSuffix := replayOrder(paths[j])
+		if iTime != jTime {
+			return iTime > jTime
+		}
+		return iSuffix > jSuffix
+	})
+	return paths, nil
+}
+
+// replayOrder returns the time that the replay at path was saved, as named by
+// SaveReplay, and its numbered suffix, or 1 if it has none.
+func replayOrder(path string) (string, int) {
+	name := ReplayName(path)
+	if len(name) > len(replayTimeLayout) {
+		suffix := strings.TrimPrefix(name[len(replayTimeLayout):], "-")
+		if n, err := strconv.Atoi(suffix); err == nil {
+			return name[:len(replayTimeLayout)], n
+		}
+	}
+	return name, 1
+}
+
+// ReplayName returns the name of the replay at path, for display.
+func ReplayName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), replayExt)
+}
+
+// ReplayPlayer plays back a Replay.
+type ReplayPlayer struct {
+	replay *Replay
+	engine *Engine
+	// The index of the next event to play.
+	next int
+}
+
+// NewReplayPlayer returns a ReplayPlayer positioned at the start of replay.
+func NewReplayPlayer(replay *Replay) *ReplayPlayer {
+	return &ReplayPlayer{
+		replay: replay,
+		engine: NewEngine(replay.Rules, replay.Seed),
+	}
+}
+
+// Engine returns the Engine that the replay is played through.
+func (p *ReplayPlayer) Engine() *Engine {
+	return p.engine
+}
+
+// Done returns true iff every tick of the replay has been played.
+func (p *ReplayPlayer) Done() bool {
+	return int(p.engine.Ticks()) >= p.replay.Ticks || p.engine.IsOver()
+}
+
+// Step plays the next tick of the replay.
+//
+// Calling Step after the replay is done does nothing.
+func (p *ReplayPlayer) Step() StepResult {
+	if p.Done() {
+		return StepResult{Over: p.engine.IsOver()}
+	}
+
+	input := NoInput
+	tick := int(p.engine.Ticks()) + 1
+	// Skip events recorded for ticks that have already been played.
+	for p.next < len(p.replay.Events) && p.replay.Events[p.next].Tick < tick {
+		p.next++
+	}
+	if p.next < len(p.replay.Events) && p.replay.Events[p.next].Tick == tick {
+		input = p.replay.Events[p.next].Input
+		p.next++
+	}
+	return p.engine.Step(input)
+}
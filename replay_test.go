@@ -0,0 +1,106 @@
+package numino
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCreateReplayFile(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 3; i++ {
+		f, err := createReplayFile(dir, "2020-01-02-030405")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		names = append(names, ReplayName(f.Name()))
+	}
+
+	want := []string{"2020-01-02-030405", "2020-01-02-030405-2", "2020-01-02-030405-3"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("replay names = %v, want %v", names, want)
+	}
+}
+
+func TestSaveReplay(t *testing.T) {
+	useTempConfigDir(t)
+
+	// Two replays saved at once are both kept, newest first.
+	var replays []*Replay
+	var paths []string
+	for seed := int64(1); seed <= 2; seed++ {
+		engine := NewEngine(DefaultRules(), seed)
+		for tick := 1; tick <= 100; tick++ {
+			engine.Step(testInput(tick))
+		}
+		path, err := SaveReplay(engine.Replay())
+		if err != nil {
+			t.Fatal(err)
+		}
+		replays = append(replays, engine.Replay())
+		paths = append(paths, path)
+	}
+
+	listed, err := ListReplays()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{paths[1], paths[0]}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("ListReplays() = %v, want %v", listed, want)
+	}
+	for i, path := range paths {
+		replay, err := LoadReplay(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(replay, replays[i]) {
+			t.Errorf("LoadReplay(%s) = %+v, want %+v", path, replay, replays[i])
+		}
+	}
+}
+
+func TestListReplaysOrder(t *testing.T) {
+	useTempConfigDir(t)
+	dir, err := ReplayDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"2020-01-02-030405",
+		"2020-01-02-030405-2",
+		"2020-01-02-030405-10",
+		"2020-01-02-030406",
+		"2019-12-31-235959",
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+replayExt), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := ListReplays()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, path := range paths {
+		got = append(got, ReplayName(path))
+	}
+	want := []string{
+		"2020-01-02-030406",
+		"2020-01-02-030405-10",
+		"2020-01-02-030405-2",
+		"2020-01-02-030405",
+		"2019-12-31-235959",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListReplays() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package numino
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Scene is a view of the game, such as a menu or a game in progress, that is
+// shown by a Router.
+//
+// Scenes never block: the Router's loop updates and draws the scene on top of
+// its stack once per frame, so that only the loop calls into the window.
+type Scene interface {
+	// OnEnter is called when the scene is pushed onto the stack, or replaces
+	// another scene, before it is first updated.
+	OnEnter(r *Router)
+	// Update handles the player's input and advances the scene by dt, the
+	// real time that passed since the last frame.
+	Update(dt time.Duration)
+	// Draw draws the scene on target.
+	Draw(target Canvas)
+	// OnExit is called when the scene is popped off the stack or replaced,
+	// or when the window is closed while it is on the stack.
+	OnExit()
+}
+
+// overlay is a Scene that is drawn over the scene beneath it, such as a pause
+// menu over a game.
+type overlay interface {
+	Scene
+	overlay()
+}
+
+// WindowInput is the player's input to a window, from the keyboard and
+// joysticks.
+type WindowInput interface {
+	JustPressed(button pixelgl.Button) bool
+	Pressed(button pixelgl.Button) bool
+	Typed() string
+	JoystickPresent(js pixelgl.Joystick) bool
+	JoystickJustPressed(js pixelgl.Joystick, button pixelgl.GamepadButton) bool
+	JoystickPressed(js pixelgl.Joystick, button pixelgl.GamepadButton) bool
+	JoystickAxis(js pixelgl.Joystick, axis pixelgl.GamepadAxis) float64
+}
+
+// Window is the window that a Router shows scenes in, such as a
+// *pixelgl.Window.
+type Window interface {
+	WindowInput
+	RenderTarget
+	Closed() bool
+	Clear(c color.Color)
+	Update()
+	Bounds() pixel.Rect
+	SetBounds(bounds pixel.Rect)
+}
+
+var _ Window = (*pixelgl.Window)(nil)
+
+// Router shows the scenes of the game in a window, one at a time.
+//
+// Scenes are kept on a stack. Push shows a scene on top of the current one
+// until it pops, and passes its result back to the scene that pushed it, which
+// is shown again. A scene can also replace itself with another scene, which
+// pops in its place.
+type Router struct {
+	win   Window
+	grid  *Grid
+	clock Clock
+	// The scenes on the stack, bottom first.
+	stack []*sceneEntry
+}
+
+// sceneEntry is a scene on the stack, and the name of its route.
+type sceneEntry struct {
+	name  string
+	scene Scene
+}
+
+// NewRouter returns a Router that shows scenes in win, with menus laid out on
+// grid, such as MenuGrid.
+func NewRouter(win Window, grid *Grid) *Router {
+	return &Router{win: win, grid: grid, clock: SystemClock}
+}
+
+// Window returns the window that scenes are shown in.
+func (r *Router) Window() Window {
+	return r.win
+}
+
+// Grid returns the grid that menus are laid out on.
+func (r *Router) Grid() *Grid {
+	return r.grid
+}
+
+// WindowBounds returns the bounds of a window that shows a board on grid and
+// its side panel.
+func WindowBounds(grid *Grid) pixel.Rect {
+	return pixel.R(0, 0, grid.PixelWidth()+SidePanelWidth(grid), grid.PixelHeight())
+}
+
+// fitWindow resizes the window to show a board on grid and its side panel.
+//
+// Scenes that show a board of a different size than Grid, such as a game or a
+// replay played with other rules, fit the window to their board when they
+// enter, and fit it back to Grid when they exit.
+func (r *Router) fitWindow(grid *Grid) {
+	if bounds := WindowBounds(grid); r.win.Bounds() != bounds {
+		r.win.SetBounds(bounds)
+	}
+}
+
+// Run pushes the scene of route, given params, then updates and draws the
+// scene on top of the stack once per frame until the stack is empty or the
+// window is closed.
+//
+// Run must be called from the function passed to pixelgl.Run, since it is the
+// only place the window is updated.
+func Run[P, R any](r *Router, route Route[P, R], params P) {
+	Push(r, route, params, nil)
+
+	last := r.clock.Now()
+	for len(r.stack) > 0 && !r.win.Closed() {
+		now := r.clock.Now()
+		r.top().scene.Update(now.Sub(last))
+		last = now
+		if len(r.stack) == 0 {
+			break
+		}
+		r.draw()
+	}
+
+	// The window was closed, so the scenes left on the stack never pop.
+	for len(r.stack) > 0 {
+		entry := r.top()
+		r.stack = r.stack[:len(r.stack)-1]
+		entry.scene.OnExit()
+	}
+}
+
+// draw draws the scene on top of the stack to the window, over the scenes
+// beneath it if it is an overlay.
+//
+// Each scene is drawn in its own buffer, so that it covers the text of the
+// scenes beneath it.
+func (r *Router) draw() {
+	first := len(r.stack) - 1
+	for first > 0 {
+		if _, ok := r.stack[first].scene.(overlay); !ok {
+			break
+		}
+		first--
+	}
+
+	r.win.Clear(ColorBg)
+	for _, entry := range r.stack[first:] {
+		imgbuf := NewImageBuffer()
+		entry.scene.Draw(imgbuf)
+		imgbuf.Renderer().Render(r.win)
+	}
+	r.win.Update()
+}
+
+// top returns the scene on top of the stack.
+func (r *Router) top() *sceneEntry {
+	return r.stack[len(r.stack)-1]
+}
+
+// mustBeTop panics if entry is not on top of the stack, since only the scene
+// on top of the stack can end.
+func (r *Router) mustBeTop(entry *sceneEntry) {
+	if len(r.stack) == 0 || r.top() != entry {
+		panic(fmt.Sprintf("scene %s ended while not on top of the stack", entry.name))
+	}
+}
+
+// Route is a named scene that is given parameters of type P, and pops with a
+// result of type R.
+type Route[P, R any] struct {
+	name     string
+	newScene func(params P, exit Exit[R]) Scene
+}
+
+// The names of all routes, so that no two routes share a name.
+var routeNames = make(map[string]bool)
+
+// NewRoute returns a route to the scenes returned by newScene. A scene ends
+// by calling the Exit it was created with.
+//
+// Routes are declared as package variables, bound to their scenes. NewRoute
+// panics if another route already has the given name.
+func NewRoute[P, R any](name string, newScene func(params P, exit Exit[R]) Scene) Route[P, R] {
+	if routeNames[name] {
+		panic(fmt.Sprintf("duplicate route: %s", name))
+	}
+	routeNames[name] = true
+	return Route[P, R]{name: name, newScene: newScene}
+}
+
+// Push shows the scene of route, given params, on top of the current scene.
+// onResult, if not nil, is called with the result the scene pops with, or the
+// result of the scenes that replaced it.
+func Push[P, R any](r *Router, route Route[P, R], params P, onResult func(R)) {
+	entry := &sceneEntry{name: route.name}
+	entry.scene = route.newScene(params, Exit[R]{router: r, entry: entry, onResult: onResult})
+	r.stack = append(r.stack, entry)
+	entry.scene.OnEnter(r)
+}
+
+// Exit ends the scene it was created for, by popping it with a result of type
+// R or by replacing it with another scene.
+//
+// A scene can only end while it is on top of the stack, and only once.
+type Exit[R any] struct {
+	router   *Router
+	entry    *sceneEntry
+	onResult func(R)
+}
+
+// Pop ends the scene, passing result to the scene that pushed it.
+func (e Exit[R]) Pop(result R) {
+	e.router.mustBeTop(e.entry)
+	e.router.stack = e.router.stack[:len(e.router.stack)-1]
+	e.entry.scene.OnExit()
+	if e.onResult != nil {
+		e.onResult(result)
+	}
+}
+
+// Replace ends the scene of e by replacing it with the scene of route, given
+// params. The replacement pops in place of the ended scene, so their results
+// have the same type.
+func Replace[P, R any](e Exit[R], route Route[P, R], params P) {
+	r := e.router
+	r.mustBeTop(e.entry)
+	e.entry.scene.OnExit()
+
+	entry := &sceneEntry{name: route.name}
+	entry.scene = route.newScene(params, Exit[R]{router: r, entry: entry, onResult: e.onResult})
+	r.stack[len(r.stack)-1] = entry
+	entry.scene.OnEnter(r)
+}
@@ -0,0 +1,208 @@
+package numino
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeWindow is a Window that is closed or open. It has no other behavior, so
+// the router must not draw to it.
+type fakeWindow struct {
+	Window
+	closed bool
+}
+
+func (w *fakeWindow) Closed() bool {
+	return w.closed
+}
+
+// testScene is a Scene that records when it enters and exits.
+type testScene struct {
+	name string
+	log  *[]string
+	exit Exit[int]
+	// update, if not nil, is called each time the scene is updated.
+	update func(s *testScene)
+}
+
+// testParams are the parameters of testRoute.
+type testParams struct {
+	name   string
+	log    *[]string
+	update func(s *testScene)
+}
+
+var testRoute = NewRoute("test", func(params testParams, exit Exit[int]) Scene {
+	return &testScene{name: params.name, log: params.log, exit: exit, update: params.update}
+})
+
+func (s *testScene) OnEnter(r *Router) {
+	*s.log = append(*s.log, s.name+" enter")
+}
+
+func (s *testScene) Update(dt time.Duration) {
+	if s.update != nil {
+		s.update(s)
+	}
+}
+
+func (s *testScene) Draw(target Canvas) {}
+
+func (s *testScene) OnExit() {
+	*s.log = append(*s.log, s.name+" exit")
+}
+
+// topScene returns the scene on top of the stack of r.
+func topScene(t *testing.T, r *Router) *testScene {
+	t.Helper()
+	if len(r.stack) == 0 {
+		t.Fatal("stack is empty")
+	}
+	return r.top().scene.(*testScene)
+}
+
+// stackNames returns the names of the scenes on the stack of r, bottom first.
+func stackNames(r *Router) []string {
+	var names []string
+	for _, entry := range r.stack {
+		names = append(names, entry.scene.(*testScene).name)
+	}
+	return names
+}
+
+func TestRouterPushPop(t *testing.T) {
+	var log []string
+	r := NewRouter(&fakeWindow{}, testGrid)
+	Push(r, testRoute, testParams{name: "a", log: &log}, nil)
+	var results []int
+	Push(r, testRoute, testParams{name: "b", log: &log}, func(result int) {
+		results = append(results, result)
+		log = append(log, "a got result")
+	})
+	if got, want := stackNames(r), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stack after Push = %v, want %v", got, want)
+	}
+
+	topScene(t, r).exit.Pop(2)
+	if got, want := stackNames(r), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stack after Pop = %v, want %v", got, want)
+	}
+	if want := []int{2}; !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+	if want := []string{"a enter", "b enter", "b exit", "a got result"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestRouterReplace(t *testing.T) {
+	var log []string
+	r := NewRouter(&fakeWindow{}, testGrid)
+	Push(r, testRoute, testParams{name: "a", log: &log}, nil)
+	var results []int
+	Push(r, testRoute, testParams{name: "b", log: &log}, func(result int) {
+		results = append(results, result)
+	})
+
+	Replace(topScene(t, r).exit, testRoute, testParams{name: "c", log: &log})
+	if got, want := stackNames(r), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stack after Replace = %v, want %v", got, want)
+	}
+	if len(results) != 0 {
+		t.Errorf("results after Replace = %v, want none", results)
+	}
+
+	// The replacement pops with the result for the scene that pushed b.
+	topScene(t, r).exit.Pop(3)
+	if want := []int{3}; !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+	if want := []string{"a enter", "b enter", "b exit", "c enter", "c exit"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestRouterEndingSceneNotOnTopPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		// end ends a scene that is not on top of the stack, where the scene
+		// b was pushed over the scene a.
+		end func(a, b *testScene)
+	}{
+		{
+			name: "pop beneath the top",
+			end: func(a, b *testScene) {
+				a.exit.Pop(1)
+			},
+		},
+		{
+			name: "replace beneath the top",
+			end: func(a, b *testScene) {
+				Replace(a.exit, testRoute, testParams{name: "c", log: a.log})
+			},
+		},
+		{
+			name: "pop twice",
+			end: func(a, b *testScene) {
+				b.exit.Pop(1)
+				b.exit.Pop(1)
+			},
+		},
+		{
+			name: "pop after replace",
+			end: func(a, b *testScene) {
+				Replace(b.exit, testRoute, testParams{name: "c", log: b.log})
+				b.exit.Pop(1)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var log []string
+			r := NewRouter(&fakeWindow{}, testGrid)
+			Push(r, testRoute, testParams{name: "a", log: &log}, nil)
+			a := topScene(t, r)
+			Push(r, testRoute, testParams{name: "b", log: &log}, nil)
+			b := topScene(t, r)
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("scene ended while not on top of the stack, want panic")
+				}
+			}()
+			test.end(a, b)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("scene pops", func(t *testing.T) {
+		var log []string
+		r := NewRouter(&fakeWindow{}, testGrid)
+		Run(r, testRoute, testParams{name: "a", log: &log, update: func(s *testScene) {
+			s.exit.Pop(0)
+		}})
+		if len(r.stack) != 0 {
+			t.Errorf("stack after Run = %v, want empty", stackNames(r))
+		}
+		if want := []string{"a enter", "a exit"}; !reflect.DeepEqual(log, want) {
+			t.Errorf("log = %v, want %v", log, want)
+		}
+	})
+
+	t.Run("window closed", func(t *testing.T) {
+		var log []string
+		r := NewRouter(&fakeWindow{closed: true}, testGrid)
+		Run(r, testRoute, testParams{name: "a", log: &log, update: func(s *testScene) {
+			t.Error("scene updated after the window closed")
+		}})
+		if len(r.stack) != 0 {
+			t.Errorf("stack after Run = %v, want empty", stackNames(r))
+		}
+		if want := []string{"a enter", "a exit"}; !reflect.DeepEqual(log, want) {
+			t.Errorf("log = %v, want %v", log, want)
+		}
+	})
+}
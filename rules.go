@@ -0,0 +1,221 @@
+package numino
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+const (
+	// ModeCustom is the game mode of games played with custom rules.
+	ModeCustom = "custom"
+
+	// The most upcoming waves that can be previewed.
+	maxPreviewWaves = 3
+)
+
+// Rules configure a game of numino.
+//
+// Times are measured in ticks, each of which is TickDuration long.
+type Rules struct {
+	// Rows and Cols are the size of the grid.
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+	// MaxLiveValue is the largest absolute value a block can hold before it
+	// dies.
+	MaxLiveValue int `json:"maxLiveValue"`
+	// SpawnChance is the probability that a block spawns in each column of a
+	// new wave at the first level.
+	SpawnChance float64 `json:"spawnChance"`
+	// MinValue and MaxValue are the range of values of new blocks at the
+	// first level. Values are distributed evenly across the range, except
+	// zero which never spawns.
+	MinValue int `json:"minValue"`
+	MaxValue int `json:"maxValue"`
+	// StartingTicksPerStep is the number of ticks it takes blocks to fall one
+	// row at the start of a game. bigger == easier.
+	StartingTicksPerStep float64 `json:"startingTicksPerStep"`
+	// SpeedupFactor is multiplied with the ticks per step at each level past
+	// the end of the level table.
+	SpeedupFactor float64 `json:"speedupFactor"`
+	// Levels decides when the game levels up, and how each level plays.
+	Levels LevelCurve `json:"levels"`
+	// PreviewWaves is the number of upcoming waves that are generated ahead
+	// of time and shown to the player.
+	PreviewWaves int `json:"previewWaves"`
+	// Scoring decides how many points the events of the game are worth.
+	Scoring Scoring `json:"scoring"`
+}
+
+// DefaultRules returns the rules of a classic game of numino.
+func DefaultRules() Rules {
+	return Rules{
+		Rows:                 9,
+		Cols:                 6,
+		MaxLiveValue:         10,
+		SpawnChance:          0.2,
+		MinValue:             -3,
+		MaxValue:             6,
+		StartingTicksPerStep: 120,
+		SpeedupFactor:        0.9,
+		Levels:               DefaultLevelCurve(),
+		PreviewWaves:         3,
+		Scoring:              DefaultScoring(),
+	}
+}
+
+// Mode returns the game mode of games played with these rules.
+//
+// Games with the default rules on any grid size are classic games.
+func (r Rules) Mode() string {
+	classic := DefaultRules()
+	classic.Rows = r.Rows
+	classic.Cols = r.Cols
+	if reflect.DeepEqual(r, classic) {
+		return ModeClassic
+	}
+	return ModeCustom
+}
+
+// The size of the smallest board. The pause menu and the controls are shown
+// over a game, in a window fit to its board, so they must fit on it.
+const (
+	minRows = 5
+	minCols = 5
+)
+
+// Validate returns an error if a game cannot be played with these rules.
+func (r Rules) Validate() error {
+	switch {
+	case r.Rows < minRows:
+		return fmt.Errorf("rows must be at least %d, got %d", minRows, r.Rows)
+	case r.Cols < minCols:
+		return fmt.Errorf("cols must be at least %d, got %d", minCols, r.Cols)
+	case r.MaxLiveValue < 1:
+		return fmt.Errorf("max live value must be at least 1, got %d", r.MaxLiveValue)
+	case r.SpeedupFactor <= 0 || r.SpeedupFactor > 1:
+		return fmt.Errorf("speedup factor must be in (0, 1], got %v", r.SpeedupFactor)
+	case r.PreviewWaves < 0 || r.PreviewWaves > maxPreviewWaves:
+		return fmt.Errorf("preview waves must be in [0, %d], got %d", maxPreviewWaves, r.PreviewWaves)
+	case r.Levels.BlocksPerLevel < 0 || r.Levels.PointsPerLevel < 0:
+		return fmt.Errorf("blocks and points per level must not be negative, got %d and %d",
+			r.Levels.BlocksPerLevel, r.Levels.PointsPerLevel)
+	}
+	if err := r.Level(1).validate(r.MaxLiveValue); err != nil {
+		return err
+	}
+	for i, level := range r.Levels.Levels {
+		if err := level.validate(r.MaxLiveValue); err != nil {
+			return fmt.Errorf("level %d: %v", i+2, err)
+		}
+	}
+	return r.Scoring.Validate()
+}
+
+// RegisterFlags defines a flag for each of these rules in fs. Each flag's
+// default value is the rule's current value.
+func (r *Rules) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&r.Rows, "rows", r.Rows, "number of rows in the grid")
+	fs.IntVar(&r.Cols, "cols", r.Cols, "number of columns in the grid")
+	fs.IntVar(&r.MaxLiveValue, "max-live-value", r.MaxLiveValue, "largest absolute value of a live block")
+	fs.Float64Var(&r.SpawnChance, "spawn-chance", r.SpawnChance, "probability that a block spawns in each column of a wave")
+	fs.IntVar(&r.MinValue, "min-value", r.MinValue, "smallest value of a new block")
+	fs.IntVar(&r.MaxValue, "max-value", r.MaxValue, "largest value of a new block")
+	fs.Float64Var(&r.StartingTicksPerStep, "ticks-per-step", r.StartingTicksPerStep, "ticks it takes blocks to fall one row at the start of a game")
+	fs.Float64Var(&r.SpeedupFactor, "speedup-factor", r.SpeedupFactor, "factor applied to the ticks per step at each level past the level table")
+	r.Levels.RegisterFlags(fs)
+	fs.IntVar(&r.PreviewWaves, "preview-waves", r.PreviewWaves, "number of upcoming waves shown")
+	r.Scoring.RegisterFlags(fs)
+}
+
+// ApplySetFlags overrides these rules with the rule flags that were set in fs.
+//
+// fs is usually a FlagSet that the flags from RegisterFlags were defined in
+// for a different Rules, such as the defaults, before the rules were loaded
+// from a file.
+func (r *Rules) ApplySetFlags(fs *flag.FlagSet) error {
+	rulesFlags := flag.NewFlagSet("rules", flag.ContinueOnError)
+	r.RegisterFlags(rulesFlags)
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err == nil && rulesFlags.Lookup(f.Name) != nil {
+			err = rulesFlags.Set(f.Name, f.Value.String())
+		}
+	})
+	return err
+}
+
+// LoadRules reads rules from the JSON file at path.
+//
+// Rules missing from the file have their default values. An error is returned
+// if the file cannot be read or the rules are invalid.
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	f, err := os.Open(path)
+	if err != nil {
+		return rules, err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return rules, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return rules, fmt.Errorf("%s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// GameFlags are the command line flags that choose the rules and seeds of
+// games: -seed, -rules, and a flag for each rule.
+type GameFlags struct {
+	fs        *flag.FlagSet
+	rules     Rules
+	seed      *int64
+	rulesFile *string
+}
+
+// RegisterGameFlags defines the game flags in fs, with the default rules as
+// the defaults of the rule flags.
+func RegisterGameFlags(fs *flag.FlagSet) *GameFlags {
+	f := &GameFlags{fs: fs, rules: DefaultRules()}
+	f.seed = fs.Int64("seed", 0, "seed used to generate blocks. If 0, a random seed is used")
+	f.rulesFile = fs.String("rules", "", "JSON file to load game rules from. Rules set by flags override the file")
+	f.rules.RegisterFlags(fs)
+	return f
+}
+
+// Rules returns the rules chosen by the flags, once they are parsed: the rules
+// file, if any, overridden by the rule flags that were set.
+//
+// An error is returned if the rules file cannot be loaded or the rules are
+// invalid.
+func (f *GameFlags) Rules() (Rules, error) {
+	rules := f.rules
+	if *f.rulesFile != "" {
+		fileRules, err := LoadRules(*f.rulesFile)
+		if err != nil {
+			return rules, err
+		}
+		if err := fileRules.ApplySetFlags(f.fs); err != nil {
+			return rules, err
+		}
+		rules = fileRules
+	}
+	return rules, rules.Validate()
+}
+
+// NewSeed returns the seed for a new game: the -seed flag if it was set, or
+// else a seed from the current time.
+func (f *GameFlags) NewSeed() int64 {
+	if *f.seed != 0 {
+		return *f.seed
+	}
+	return time.Now().UTC().UnixNano()
+}
@@ -0,0 +1,98 @@
+package numino
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGameFlags(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.json")
+	if err := ioutil.WriteFile(rulesFile, []byte(`{"rows": 12, "cols": 8}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantRows int
+		wantCols int
+		wantSeed int64
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			wantRows: DefaultRules().Rows,
+			wantCols: DefaultRules().Cols,
+		},
+		{
+			name:     "rule flags",
+			args:     []string{"-rows", "7", "-seed", "3"},
+			wantRows: 7,
+			wantCols: DefaultRules().Cols,
+			wantSeed: 3,
+		},
+		{
+			name:     "rules file",
+			args:     []string{"-rules", rulesFile},
+			wantRows: 12,
+			wantCols: 8,
+		},
+		{
+			name:     "flags override rules file",
+			args:     []string{"-rules", rulesFile, "-cols", "5"},
+			wantRows: 12,
+			wantCols: 5,
+		},
+		{
+			name:    "invalid rules",
+			args:    []string{"-rows", "1"},
+			wantErr: true,
+		},
+		{
+			name:    "too few rows for the pause menu",
+			args:    []string{"-rows", "4"},
+			wantErr: true,
+		},
+		{
+			name:    "too few cols for the pause menu",
+			args:    []string{"-cols", "4"},
+			wantErr: true,
+		},
+		{
+			name:    "missing rules file",
+			args:    []string{"-rules", filepath.Join(t.TempDir(), "missing.json")},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			gameFlags := RegisterGameFlags(fs)
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			rules, err := gameFlags.Rules()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Rules() = %+v, want error", rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rules() failed: %v", err)
+			}
+			if rules.Rows != test.wantRows || rules.Cols != test.wantCols {
+				t.Errorf("Rules() is %dx%d, want %dx%d", rules.Cols, rules.Rows, test.wantCols, test.wantRows)
+			}
+			if test.wantSeed != 0 {
+				if seed := gameFlags.NewSeed(); seed != test.wantSeed {
+					t.Errorf("NewSeed() = %d, want %d", seed, test.wantSeed)
+				}
+			}
+		})
+	}
+}
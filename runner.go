@@ -0,0 +1,117 @@
+package numino
+
+// Runner advances an Engine in real time.
+//
+// Inputs are queued until the next tick, so that none are lost when the game
+// is updated more often than it ticks.
+type Runner struct {
+	engine   *Engine
+	timestep *Timestep
+	inputs   []Input
+
+	// The input the player is holding down, the ticks it has been held for,
+	// and how it repeats.
+	held      Input
+	heldTicks int
+	autoShift AutoShift
+}
+
+// AutoShift configures how an input repeats while the player holds it down:
+// after an initial delay, the input repeats at a steady rate. Both are counted
+// in ticks, so that held inputs play out the same way in replays.
+type AutoShift struct {
+	// Delay is the number of ticks an input must be held before it starts
+	// to repeat. Zero means that held inputs never repeat.
+	Delay int `json:"delay"`
+	// Rate is the number of ticks between each repeat.
+	Rate int `json:"rate"`
+}
+
+// DefaultAutoShift returns the auto shift of a new player.
+func DefaultAutoShift() AutoShift {
+	return AutoShift{Delay: 10, Rate: 2}
+}
+
+// NewRunner returns a Runner that advances engine by the time that passes on
+// clock.
+func NewRunner(engine *Engine, clock Clock) *Runner {
+	return &Runner{
+		engine:   engine,
+		timestep: NewTimestep(clock),
+	}
+}
+
+// Engine returns the Engine advanced by this Runner.
+func (r *Runner) Engine() *Engine {
+	return r.engine
+}
+
+// Input queues an input to be applied in the next tick without input.
+func (r *Runner) Input(input Input) {
+	if input != NoInput {
+		r.inputs = append(r.inputs, input)
+	}
+}
+
+// SetAutoShift sets how held inputs repeat. By default they do not.
+func (r *Runner) SetAutoShift(autoShift AutoShift) {
+	r.autoShift = autoShift
+}
+
+// Hold sets the input that the player is holding down, or NoInput if they are
+// not holding one. It should be called on every update, after the input was
+// first given to Input.
+//
+// A held input repeats according to the auto shift. Holding a different input
+// starts its delay over.
+func (r *Runner) Hold(input Input) {
+	if input != r.held {
+		r.held = input
+		r.heldTicks = 0
+	}
+}
+
+// Update steps the engine once for every tick that has elapsed since the last
+// update, and returns the result of each step.
+//
+// Stepping stops when the game is over.
+func (r *Runner) Update() []StepResult {
+	var results []StepResult
+	for ticks := r.timestep.Update(); ticks > 0 && !r.engine.IsOver(); ticks-- {
+		repeat := r.updateHeld()
+		input := NoInput
+		if len(r.inputs) > 0 {
+			input = r.inputs[0]
+			r.inputs = r.inputs[1:]
+		} else if repeat {
+			// Repeats are dropped while other inputs are queued, so that
+			// they cannot pile up.
+			input = r.held
+		}
+		results = append(results, r.engine.Step(input))
+	}
+	return results
+}
+
+// updateHeld counts a tick of the held input, and returns true iff the held
+// input repeats in this tick.
+func (r *Runner) updateHeld() bool {
+	if r.held == NoInput || r.autoShift.Delay <= 0 {
+		return false
+	}
+	r.heldTicks++
+	if r.heldTicks < r.autoShift.Delay {
+		return false
+	}
+	return (r.heldTicks-r.autoShift.Delay)%r.autoShift.Rate == 0
+}
+
+// Pause discards the time that has elapsed since the last update, so that
+// the game does not advance while paused, along with any queued inputs. The
+// held input is released.
+func (r *Runner) Pause() {
+	r.timestep.Reset()
+	r.inputs = nil
+	r.held = NoInput
+	r.heldTicks = 0
+}
@@ -0,0 +1,143 @@
+package numino
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestRunner returns a Runner for a new game on a manual clock, with the
+// given auto shift.
+func newTestRunner(autoShift AutoShift) (*Runner, *ManualClock) {
+	clock := &ManualClock{}
+	runner := NewRunner(NewEngine(DefaultRules(), 1), clock)
+	runner.SetAutoShift(autoShift)
+	return runner, clock
+}
+
+// tickRunner advances clock by the given number of ticks and updates runner
+// once.
+func tickRunner(runner *Runner, clock *ManualClock, ticks int) {
+	clock.Advance(TickDuration * time.Duration(ticks))
+	runner.Update()
+}
+
+// appliedInputs returns the inputs that runner applied to its engine, and the
+// ticks they were applied in.
+func appliedInputs(runner *Runner) []ReplayEvent {
+	return runner.Engine().Replay().Events
+}
+
+func TestRunnerAutoShift(t *testing.T) {
+	runner, clock := newTestRunner(AutoShift{Delay: 10, Rate: 2})
+
+	// The player presses shift right and holds it for 12 ticks, one update
+	// per tick.
+	runner.Input(ShiftRightInput)
+	for i := 0; i < 12; i++ {
+		runner.Hold(ShiftRightInput)
+		tickRunner(runner, clock, 1)
+	}
+	// They release it for 3 ticks, then hold it again for 15 ticks.
+	for i := 0; i < 3; i++ {
+		runner.Hold(NoInput)
+		tickRunner(runner, clock, 1)
+	}
+	for i := 0; i < 15; i++ {
+		runner.Hold(ShiftRightInput)
+		tickRunner(runner, clock, 1)
+	}
+
+	// The press shifts at once. The first repeat comes once the shift has
+	// been held for Delay ticks, then every Rate ticks. Releasing starts the
+	// delay over.
+	want := []ReplayEvent{
+		{Tick: 1, Input: ShiftRightInput},
+		{Tick: 10, Input: ShiftRightInput},
+		{Tick: 12, Input: ShiftRightInput},
+		{Tick: 25, Input: ShiftRightInput},
+		{Tick: 27, Input: ShiftRightInput},
+		{Tick: 29, Input: ShiftRightInput},
+	}
+	if got := appliedInputs(runner); !reflect.DeepEqual(got, want) {
+		t.Errorf("applied inputs = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerAutoShiftSameInAnyUpdates(t *testing.T) {
+	// Held inputs repeat by ticks, so updating once per tick or once for
+	// many ticks applies the same inputs.
+	perTick, perTickClock := newTestRunner(DefaultAutoShift())
+	perTick.Input(ShiftLeftInput)
+	perTick.Hold(ShiftLeftInput)
+	for i := 0; i < 20; i++ {
+		tickRunner(perTick, perTickClock, 1)
+	}
+
+	batched, batchedClock := newTestRunner(DefaultAutoShift())
+	batched.Input(ShiftLeftInput)
+	batched.Hold(ShiftLeftInput)
+	tickRunner(batched, batchedClock, 20)
+
+	if got, want := appliedInputs(batched), appliedInputs(perTick); !reflect.DeepEqual(got, want) {
+		t.Errorf("applied inputs = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerAutoShiftDisabled(t *testing.T) {
+	runner, clock := newTestRunner(AutoShift{Delay: 0, Rate: 1})
+	runner.Input(ShiftRightInput)
+	runner.Hold(ShiftRightInput)
+	tickRunner(runner, clock, 20)
+
+	want := []ReplayEvent{{Tick: 1, Input: ShiftRightInput}}
+	if got := appliedInputs(runner); !reflect.DeepEqual(got, want) {
+		t.Errorf("applied inputs = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerRepeatsDoNotPileUp(t *testing.T) {
+	runner, clock := newTestRunner(AutoShift{Delay: 1, Rate: 1})
+
+	// Shift right is held so that it repeats every tick, while three shift
+	// lefts are queued.
+	runner.Hold(ShiftRightInput)
+	runner.Input(ShiftLeftInput)
+	runner.Input(ShiftLeftInput)
+	runner.Input(ShiftLeftInput)
+	tickRunner(runner, clock, 5)
+
+	// The repeats that came due while the shift lefts were queued are
+	// dropped rather than applied afterwards.
+	want := []ReplayEvent{
+		{Tick: 1, Input: ShiftLeftInput},
+		{Tick: 2, Input: ShiftLeftInput},
+		{Tick: 3, Input: ShiftLeftInput},
+		{Tick: 4, Input: ShiftRightInput},
+		{Tick: 5, Input: ShiftRightInput},
+	}
+	if got := appliedInputs(runner); !reflect.DeepEqual(got, want) {
+		t.Errorf("applied inputs = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerPause(t *testing.T) {
+	runner, clock := newTestRunner(AutoShift{Delay: 1, Rate: 1})
+	runner.Input(SlamInput)
+	runner.Hold(ShiftRightInput)
+	clock.Advance(10 * TickDuration)
+
+	// Pausing drops the time since the last update, the queued input and the
+	// held input.
+	runner.Pause()
+	if steps := runner.Update(); len(steps) != 0 {
+		t.Errorf("Update() after Pause() stepped %d times, want 0", len(steps))
+	}
+	tickRunner(runner, clock, 3)
+	if got := appliedInputs(runner); len(got) != 0 {
+		t.Errorf("applied inputs = %v, want none", got)
+	}
+	if got := runner.Engine().Stats().Duration; got != 3*TickDuration {
+		t.Errorf("game duration = %v, want %v", got, 3*TickDuration)
+	}
+}
@@ -0,0 +1,256 @@
+package numino
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The current version of the save file format.
+const saveGameVersion = 5
+
+// SaveGame is a snapshot of an in-progress game.
+type SaveGame struct {
+	Version int   `json:"version"`
+	Rules   Rules `json:"rules"`
+	// Blocks and BlockState are the blocks that have been placed on the grid.
+	Blocks     [][]int        `json:"blocks"`
+	BlockState [][]BlockState `json:"blockState"`
+	// FallingBlocks are the blocks under player control.
+	FallingBlocks []Block `json:"fallingBlocks"`
+	// Upcoming are the waves that have been generated ahead of time, in
+	// order.
+	Upcoming [][]Block `json:"upcoming"`
+	// Held is the wave in the hold slot, and HoldUsed is true if the falling
+	// blocks came into play by holding.
+	Held     []Block `json:"held"`
+	HoldUsed bool    `json:"holdUsed"`
+	// CounterTicks and CounterLastQuantum are the values of the falling blocks'
+	// counter.
+	CounterTicks       float64 `json:"counterTicks"`
+	CounterLastQuantum float64 `json:"counterLastQuantum"`
+	// Seed and Draws are the state of the random number generator: the seed
+	// it was created with, and the number of values drawn from it.
+	Seed  int64 `json:"seed"`
+	Draws int64 `json:"draws"`
+
+	Ticks  float64       `json:"ticks"`
+	Points ScorePoints   `json:"points"`
+	Level  int           `json:"level"`
+	Events []ReplayEvent `json:"events"`
+
+	// Counters for the game's Stats.
+	BlocksLanded    int     `json:"blocksLanded"`
+	Merges          int     `json:"merges"`
+	Deaths          int     `json:"deaths"`
+	Zeroes          int     `json:"zeroes"`
+	MaxChain        int     `json:"maxChain"`
+	LastDeath       float64 `json:"lastDeath"`
+	LongestSurvival float64 `json:"longestSurvival"`
+	PeakSpeed       float64 `json:"peakSpeed"`
+}
+
+// Save returns a snapshot of this game.
+func (e *Engine) Save() *SaveGame {
+	save := &SaveGame{
+		Version:            saveGameVersion,
+		Rules:              e.rules,
+		Blocks:             make([][]int, e.game.RowCount()),
+		BlockState:         make([][]BlockState, e.game.RowCount()),
+		FallingBlocks:      e.fallingBlocks.Blocks(),
+		Upcoming:           make([][]Block, len(e.fallingBlocks.upcoming)),
+		Held:               e.fallingBlocks.Held(),
+		HoldUsed:           e.fallingBlocks.holdUsed,
+		CounterTicks:       e.fallingBlocks.counter.Ticks,
+		CounterLastQuantum: e.fallingBlocks.counter.lastQuantum,
+		Seed:               e.fallingBlocks.source.seed,
+		Draws:              e.fallingBlocks.source.draws,
+		Ticks:              e.ticks,
+		Points:             e.points,
+		Level:              e.level,
+		Events:             e.Replay().Events,
+		BlocksLanded:       e.blocksLanded,
+		Merges:             e.merges,
+		Deaths:             e.deaths,
+		Zeroes:             e.zeroes,
+		MaxChain:           e.maxChain,
+		LastDeath:          e.lastDeath,
+		LongestSurvival:    e.longestSurvival,
+		PeakSpeed:          e.peakSpeed,
+	}
+	for i, wave := range e.fallingBlocks.upcoming {
+		save.Upcoming[i] = append([]Block(nil), wave...)
+	}
+	for i := range e.game.blocks {
+		save.Blocks[i] = append([]int(nil), e.game.blocks[i]...)
+		save.BlockState[i] = append([]BlockState(nil), e.game.blockState[i]...)
+	}
+	return save
+}
+
+// RestoreEngine returns an Engine that continues the game in the given save.
+func RestoreEngine(save *SaveGame) (*Engine, error) {
+	if save.Version != saveGameVersion {
+		return nil, fmt.Errorf("unsupported save version: %d", save.Version)
+	}
+	if err := save.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid save rules: %v", err)
+	}
+	rows, cols := save.Rules.Rows, save.Rules.Cols
+	if len(save.Blocks) != rows || len(save.BlockState) != rows {
+		return nil, fmt.Errorf("invalid save grid: %d rows", len(save.Blocks))
+	}
+	for i := 0; i < rows; i++ {
+		if len(save.Blocks[i]) != cols || len(save.BlockState[i]) != cols {
+			return nil, fmt.Errorf("invalid save grid: row %d", i)
+		}
+	}
+	for _, block := range save.FallingBlocks {
+		if block.Row < 0 || block.Row >= rows || block.Col < 0 || block.Col >= cols {
+			return nil, fmt.Errorf("invalid falling block: %v", block)
+		}
+	}
+	for _, wave := range save.Upcoming {
+		if err := validateWave(wave, cols); err != nil {
+			return nil, fmt.Errorf("invalid upcoming wave: %v", err)
+		}
+	}
+	if err := validateWave(save.Held, cols); err != nil {
+		return nil, fmt.Errorf("invalid held wave: %v", err)
+	}
+	if save.Level < 1 {
+		return nil, fmt.Errorf("invalid save level: %d", save.Level)
+	}
+	if save.PeakSpeed <= 0 {
+		return nil, fmt.Errorf("invalid save peak speed: %v", save.PeakSpeed)
+	}
+
+	e := &Engine{
+		rules:         save.Rules,
+		game:          NewGameState(rows, cols, save.Rules.MaxLiveValue),
+		fallingBlocks: newFallingBlocks(save.Rules, save.Seed),
+		seed:          save.Seed,
+		ticks:         save.Ticks,
+		points:        save.Points,
+		level:         save.Level,
+		events:        append([]ReplayEvent(nil), save.Events...),
+
+		blocksLanded:    save.BlocksLanded,
+		merges:          save.Merges,
+		deaths:          save.Deaths,
+		zeroes:          save.Zeroes,
+		maxChain:        save.MaxChain,
+		lastDeath:       save.LastDeath,
+		longestSurvival: save.LongestSurvival,
+		peakSpeed:       save.PeakSpeed,
+	}
+	for i := 0; i < rows; i++ {
+		copy(e.game.blocks[i], save.Blocks[i])
+		copy(e.game.blockState[i], save.BlockState[i])
+	}
+	for _, block := range save.FallingBlocks {
+		e.fallingBlocks.Add(block.Row, block.Col, block.Value)
+	}
+	e.fallingBlocks.level = save.Rules.Level(save.Level)
+	e.fallingBlocks.counter.Ticks = save.CounterTicks
+	e.fallingBlocks.counter.lastQuantum = save.CounterLastQuantum
+	e.fallingBlocks.source.Skip(save.Draws)
+	// Generate any upcoming waves that are missing from the save.
+	e.fallingBlocks.upcoming = save.Upcoming
+	e.fallingBlocks.held = save.Held
+	e.fallingBlocks.holdUsed = save.HoldUsed
+	e.fallingBlocks.fillUpcoming()
+	return e, nil
+}
+
+// validateWave returns an error if wave is not a wave of blocks in the first
+// row of a grid with the given number of columns.
+func validateWave(wave []Block, cols int) error {
+	for _, block := range wave {
+		if block.Row != 0 || block.Col < 0 || block.Col >= cols {
+			return fmt.Errorf("invalid block: %v", block)
+		}
+	}
+	return nil
+}
+
+// WriteSaveGame writes the given save to w.
+func WriteSaveGame(w io.Writer, save *SaveGame) error {
+	return json.NewEncoder(w).Encode(save)
+}
+
+// ReadSaveGame reads a save from r.
+func ReadSaveGame(r io.Reader) (*SaveGame, error) {
+	// Rules missing from the file, such as scoring, have their default values.
+	save := SaveGame{Rules: DefaultRules()}
+	if err := json.NewDecoder(r).Decode(&save); err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// SaveGamePath returns the path of the file where an in-progress game is
+// saved.
+func SaveGamePath() (string, error) {
+	return configPath("save.json")
+}
+
+// StoreSaveGame writes the given save to SaveGamePath, replacing any
+// existing save.
+func StoreSaveGame(save *SaveGame) error {
+	path, err := SaveGamePath()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteSaveGame(w, save)
+	})
+}
+
+// LoadSaveGame reads the save at SaveGamePath.
+func LoadSaveGame() (*SaveGame, error) {
+	path, err := SaveGamePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSaveGame(f)
+}
+
+// HasSaveGame returns true iff there is a save at SaveGamePath.
+func HasSaveGame() bool {
+	path, err := SaveGamePath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// DeleteSaveGame deletes the save at SaveGamePath, if any.
+func DeleteSaveGame() error {
+	path, err := SaveGamePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DiscardSaveGame moves the save at SaveGamePath aside, for a save that cannot
+// be continued. It is no longer offered to continue, but is kept next to where
+// it was, with a .corrupt suffix, so that it can be recovered by hand.
+func DiscardSaveGame() error {
+	path, err := SaveGamePath()
+	if err != nil {
+		return err
+	}
+	return os.Rename(path, path+".corrupt")
+}
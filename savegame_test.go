@@ -0,0 +1,166 @@
+package numino
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testInput returns the input applied in the given tick of test games, so
+// that they shift, hold and slam.
+func testInput(tick int) Input {
+	switch {
+	case tick%29 == 0:
+		return SlamInput
+	case tick%13 == 0:
+		return HoldInput
+	case tick%11 == 0:
+		return ShiftRightInput
+	case tick%7 == 0:
+		return ShiftLeftInput
+	}
+	return NoInput
+}
+
+// saveAndRestore saves engine, writes and reads the save, and returns an
+// engine restored from it.
+func saveAndRestore(t *testing.T, engine *Engine) *Engine {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteSaveGame(&buf, engine.Save()); err != nil {
+		t.Fatal(err)
+	}
+	save, err := ReadSaveGame(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreEngine(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return restored
+}
+
+func TestSaveGameRoundTrip(t *testing.T) {
+	const saveTick = 400
+	const endTick = 1200
+
+	// An unbroken game is played alongside one that is saved and restored
+	// partway through. Both must play out the same.
+	unbroken := NewEngine(DefaultRules(), 42)
+	restored := NewEngine(DefaultRules(), 42)
+	for tick := 1; tick <= endTick; tick++ {
+		if tick == saveTick {
+			restored = saveAndRestore(t, restored)
+		}
+		input := testInput(tick)
+		want := unbroken.Step(input)
+		got := restored.Step(input)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("tick %d: Step(%v) = %+v, want %+v", tick, input, got, want)
+		}
+	}
+
+	if got, want := restored.Save(), unbroken.Save(); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored game ended as %+v, want %+v", got, want)
+	}
+	if got, want := restored.Stats(), unbroken.Stats(); got != want {
+		t.Errorf("restored game stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestRestoreEngineRejectsInvalidSaves(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(save *SaveGame)
+	}{
+		{
+			name:   "wrong version",
+			modify: func(save *SaveGame) { save.Version = saveGameVersion - 1 },
+		},
+		{
+			name:   "invalid rules",
+			modify: func(save *SaveGame) { save.Rules.Rows = 0 },
+		},
+		{
+			name:   "missing row",
+			modify: func(save *SaveGame) { save.Blocks = save.Blocks[1:] },
+		},
+		{
+			name: "short row",
+			modify: func(save *SaveGame) {
+				save.BlockState[2] = save.BlockState[2][1:]
+			},
+		},
+		{
+			name: "falling block off the board",
+			modify: func(save *SaveGame) {
+				save.FallingBlocks = append(save.FallingBlocks, Block{Row: 0, Col: save.Rules.Cols, Value: 1})
+			},
+		},
+		{
+			name: "upcoming block off the board",
+			modify: func(save *SaveGame) {
+				save.Upcoming = [][]Block{{{Row: 0, Col: -1, Value: 1}}}
+			},
+		},
+		{
+			name: "held block below the first row",
+			modify: func(save *SaveGame) {
+				save.Held = []Block{{Row: 1, Col: 0, Value: 1}}
+			},
+		},
+		{
+			name:   "invalid level",
+			modify: func(save *SaveGame) { save.Level = 0 },
+		},
+		{
+			name:   "missing peak speed",
+			modify: func(save *SaveGame) { save.PeakSpeed = 0 },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(DefaultRules(), 1)
+			for tick := 1; tick <= 100; tick++ {
+				engine.Step(testInput(tick))
+			}
+			save := engine.Save()
+			if _, err := RestoreEngine(save); err != nil {
+				t.Fatalf("RestoreEngine() of unmodified save: %v", err)
+			}
+
+			test.modify(save)
+			if _, err := RestoreEngine(save); err == nil {
+				t.Error("RestoreEngine() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestDiscardSaveGame(t *testing.T) {
+	useTempConfigDir(t)
+	if err := StoreSaveGame(NewEngine(DefaultRules(), 1).Save()); err != nil {
+		t.Fatal(err)
+	}
+	if !HasSaveGame() {
+		t.Fatal("HasSaveGame() = false after StoreSaveGame()")
+	}
+
+	if err := DiscardSaveGame(); err != nil {
+		t.Fatal(err)
+	}
+	// The save is no longer offered to continue, but is kept aside.
+	if HasSaveGame() {
+		t.Error("HasSaveGame() = true after DiscardSaveGame()")
+	}
+	path, err := SaveGamePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("discarded save was not kept: %v", err)
+	}
+}
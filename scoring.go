@@ -0,0 +1,103 @@
+package numino
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+// Scoring decides how many points the events of a game are worth.
+//
+// Scoring is part of a game's Rules, so that different scoring can be
+// compared by playing or replaying the same game with each.
+type Scoring struct {
+	// Land is awarded for each block that lands.
+	Land int `json:"land"`
+	// Merge is awarded for each block that merges with a live block.
+	Merge int `json:"merge"`
+	// Zero is awarded for each cell that is brought to exactly zero.
+	Zero int `json:"zero"`
+	// ChainMultiplier is how many times more points each step of a chain is
+	// worth than the step before it. The first step is worth normal points.
+	ChainMultiplier float64 `json:"chainMultiplier"`
+	// Death is taken away for each block that dies.
+	Death int `json:"death"`
+}
+
+// DefaultScoring returns the scoring of a classic game of numino.
+func DefaultScoring() Scoring {
+	return Scoring{
+		Land:            1,
+		Merge:           2,
+		Zero:            10,
+		ChainMultiplier: 2,
+		Death:           5,
+	}
+}
+
+// Validate returns an error if a game cannot be scored with this scoring.
+func (s Scoring) Validate() error {
+	switch {
+	case s.Land < 0 || s.Merge < 0 || s.Zero < 0 || s.Death < 0:
+		return fmt.Errorf("points must not be negative, got %+v", s)
+	case s.ChainMultiplier < 1:
+		return fmt.Errorf("chain multiplier must be at least 1, got %v", s.ChainMultiplier)
+	}
+	return nil
+}
+
+// RegisterFlags defines a flag for each field of this scoring in fs. Each
+// flag's default value is the field's current value.
+func (s *Scoring) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&s.Land, "score-land", s.Land, "points for each block that lands")
+	fs.IntVar(&s.Merge, "score-merge", s.Merge, "points for each block that merges with a live block")
+	fs.IntVar(&s.Zero, "score-zero", s.Zero, "points for each cell brought to zero")
+	fs.Float64Var(&s.ChainMultiplier, "score-chain-multiplier", s.ChainMultiplier, "multiplier applied to the points of each step of a chain")
+	fs.IntVar(&s.Death, "score-death", s.Death, "points taken away for each block that dies")
+}
+
+// ScorePoints are the points of a game, by where they were earned.
+type ScorePoints struct {
+	Land  int `json:"land"`
+	Merge int `json:"merge"`
+	Zero  int `json:"zero"`
+	// Chain is the extra points earned from chain multipliers.
+	Chain int `json:"chain"`
+	// Death is the points taken away for deaths, as a negative number.
+	Death int `json:"death"`
+}
+
+// Total returns the score that these points add up to.
+func (p ScorePoints) Total() int {
+	return p.Land + p.Merge + p.Zero + p.Chain + p.Death
+}
+
+// Add adds the given points to these points.
+func (p *ScorePoints) Add(q ScorePoints) {
+	p.Land += q.Land
+	p.Merge += q.Merge
+	p.Zero += q.Zero
+	p.Chain += q.Chain
+	p.Death += q.Death
+}
+
+// Points returns the points for the events of a single step: the blocks that
+// landed, and how many of them merged and died, followed by the chain that
+// resolved the grid.
+func (s Scoring) Points(landed int, merged int, died int, chain []ChainStep) ScorePoints {
+	points := ScorePoints{
+		Land:  landed * s.Land,
+		Merge: merged * s.Merge,
+		Death: -died * s.Death,
+	}
+	multiplier := 1.0
+	for _, step := range chain {
+		base := len(step.Cleared)*s.Zero + len(step.Merged)*s.Merge
+		points.Zero += len(step.Cleared) * s.Zero
+		points.Merge += len(step.Merged) * s.Merge
+		points.Chain += int(math.Round(float64(base)*multiplier)) - base
+		points.Death -= len(step.Died) * s.Death
+		multiplier *= s.ChainMultiplier
+	}
+	return points
+}
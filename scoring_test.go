@@ -0,0 +1,91 @@
+package numino
+
+import "testing"
+
+func TestScoringPoints(t *testing.T) {
+	// cells returns n cells, for the length of a chain step's fields.
+	cells := func(n int) []Block {
+		return make([]Block, n)
+	}
+
+	tests := []struct {
+		name    string
+		scoring Scoring
+		landed  int
+		merged  int
+		died    int
+		chain   []ChainStep
+		want    ScorePoints
+	}{
+		{
+			name:    "land and merge",
+			scoring: DefaultScoring(),
+			landed:  3,
+			merged:  2,
+			want:    ScorePoints{Land: 3, Merge: 4},
+		},
+		{
+			name:    "death with merges",
+			scoring: DefaultScoring(),
+			landed:  2,
+			merged:  2,
+			died:    1,
+			want:    ScorePoints{Land: 2, Merge: 4, Death: -5},
+		},
+		{
+			name:    "first step is not multiplied",
+			scoring: DefaultScoring(),
+			landed:  1,
+			merged:  1,
+			chain:   []ChainStep{{Cleared: cells(1)}},
+			want:    ScorePoints{Land: 1, Merge: 2, Zero: 10},
+		},
+		{
+			name:    "each step is multiplied again",
+			scoring: DefaultScoring(),
+			landed:  1,
+			merged:  1,
+			chain: []ChainStep{
+				{Cleared: cells(1), Merged: cells(1)},
+				{Cleared: cells(1), Merged: cells(1)},
+				{Cleared: cells(2)},
+			},
+			// Step 2 is worth 12 points doubled, and step 3 20 points
+			// quadrupled.
+			want: ScorePoints{Land: 1, Merge: 2 + 4, Zero: 40, Chain: 12 + 60},
+		},
+		{
+			name:    "chain points are rounded",
+			scoring: Scoring{Merge: 1, Zero: 1, ChainMultiplier: 1.5},
+			chain: []ChainStep{
+				{Cleared: cells(1)},
+				// 3 * 1.5 = 4.5 rounds to 5.
+				{Cleared: cells(2), Merged: cells(1)},
+				// 1 * 2.25 rounds to 2.
+				{Cleared: cells(1)},
+			},
+			want: ScorePoints{Merge: 1, Zero: 4, Chain: 2 + 1},
+		},
+		{
+			name:    "deaths in chain are not multiplied",
+			scoring: DefaultScoring(),
+			landed:  1,
+			merged:  1,
+			died:    1,
+			chain: []ChainStep{
+				{Cleared: cells(1)},
+				{Merged: cells(2), Died: cells(2)},
+			},
+			want: ScorePoints{Land: 1, Merge: 2 + 4, Zero: 10, Chain: 4, Death: -15},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.scoring.Points(test.landed, test.merged, test.died, test.chain)
+			if got != test.want {
+				t.Errorf("Points() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package numino
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// TerminalCanvas is a Canvas that draws to a grid of terminal characters.
+//
+// Each square of a Grid covers one row of characters. Shapes fill the
+// characters whose centers they cover, and text is written one character per
+// column. Characters are much wider than a pixel font, so text drawn within a
+// square must be checked against TextWidth, or it runs into the next square.
+type TerminalCanvas struct {
+	// The characters of the canvas, top row first.
+	chars      [][]terminalChar
+	charWidth  float64
+	charHeight float64
+}
+
+type terminalChar struct {
+	char rune
+	fg   color.RGBA
+	bg   color.RGBA
+}
+
+// NewTerminalCanvas returns a canvas for drawing grid, where each square of
+// the grid is squareWidth characters wide. The canvas is filled with ColorBg.
+func NewTerminalCanvas(grid *Grid, squareWidth int) *TerminalCanvas {
+	c := &TerminalCanvas{
+		chars:      make([][]terminalChar, grid.Rows),
+		charWidth:  grid.SquareSize / float64(squareWidth),
+		charHeight: grid.SquareSize,
+	}
+	for i := range c.chars {
+		c.chars[i] = make([]terminalChar, grid.Cols*squareWidth)
+	}
+	c.Clear(ColorBg)
+	return c
+}
+
+// Clear fills this canvas with the given color.
+func (c *TerminalCanvas) Clear(col color.RGBA) {
+	for _, row := range c.chars {
+		for i := range row {
+			row[i] = terminalChar{char: ' ', fg: ColorText, bg: col}
+		}
+	}
+}
+
+// Rect implements the Canvas interface.
+func (c *TerminalCanvas) Rect(x, y, w, h float64, col color.RGBA) {
+	for i, row := range c.chars {
+		centerY := c.height() - (float64(i)+0.5)*c.charHeight
+		if centerY < y || centerY >= y+h {
+			continue
+		}
+		for j := range row {
+			centerX := (float64(j) + 0.5) * c.charWidth
+			if centerX < x || centerX >= x+w {
+				continue
+			}
+			row[j].bg = blend(col, row[j].bg)
+			if col.A == 0xff {
+				row[j].char = ' '
+			} else {
+				row[j].fg = blend(col, row[j].fg)
+			}
+		}
+	}
+}
+
+// Text implements the Canvas interface.
+func (c *TerminalCanvas) Text(x, y float64, msg string, col color.RGBA) {
+	i := int(math.Floor((c.height() - y) / c.charHeight))
+	if i < 0 || i >= len(c.chars) {
+		return
+	}
+	row := c.chars[i]
+	j := int(math.Floor(x / c.charWidth))
+	for _, char := range msg {
+		if j >= len(row) {
+			break
+		}
+		if j >= 0 {
+			row[j].char = char
+			row[j].fg = col
+		}
+		j++
+	}
+}
+
+// TextWidth implements the Canvas interface.
+func (c *TerminalCanvas) TextWidth(msg string) float64 {
+	return float64(utf8.RuneCountInString(msg)) * c.charWidth
+}
+
+// Lines returns the rows of this canvas as text, top first, using ANSI escape
+// sequences for colors. Each line ends by resetting the style.
+func (c *TerminalCanvas) Lines() []string {
+	lines := make([]string, len(c.chars))
+	for i, row := range c.chars {
+		var b strings.Builder
+		var fg, bg color.RGBA
+		for j, char := range row {
+			if j == 0 || char.bg != bg {
+				bg = char.bg
+				fmt.Fprintf(&b, "\x1b[48;2;%d;%d;%dm", bg.R, bg.G, bg.B)
+			}
+			if j == 0 || char.fg != fg {
+				fg = char.fg
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm", fg.R, fg.G, fg.B)
+			}
+			b.WriteRune(char.char)
+		}
+		b.WriteString("\x1b[0m")
+		lines[i] = b.String()
+	}
+	return lines
+}
+
+func (c *TerminalCanvas) height() float64 {
+	return float64(len(c.chars)) * c.charHeight
+}
+
+// blend returns the color src drawn over dst. Both colors are
+// alpha-premultiplied.
+func blend(src color.RGBA, dst color.RGBA) color.RGBA {
+	a := 0xff - uint32(src.A)
+	return color.RGBA{
+		R: src.R + uint8(uint32(dst.R)*a/0xff),
+		G: src.G + uint8(uint32(dst.G)*a/0xff),
+		B: src.B + uint8(uint32(dst.B)*a/0xff),
+		A: src.A + uint8(uint32(dst.A)*a/0xff),
+	}
+}
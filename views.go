@@ -1,284 +1,1092 @@
 package numino
 
 import (
+	_ "embed"
+	"fmt"
 	"image/color"
 	"log"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/faiface/pixel/pixelgl"
 )
 
-// ViewGame runs the numino game.
-func ViewGame(win *pixelgl.Window, grid *Grid, done chan GoToCmd) {
-	LoadSounds()
-	bgMusic := LoopSound(BackgroundMusic)
-	defer StopSound(bgMusic)
+// The routes to each scene of the game.
+//
+// Scenes refer to the routes of the scenes they lead to, so the routes are
+// bound in init to break the initialization cycle.
+var (
+	// MenuRoute runs the main menu. It pops when the player chooses to exit.
+	MenuRoute Route[MenuParams, struct{}]
+	// GameRoute runs a new game.
+	GameRoute Route[GameParams, struct{}]
+	// ContinueRoute resumes the game that was saved when the player last
+	// quit. Its parameter returns the seed of each new game started after the
+	// saved one.
+	ContinueRoute Route[func() int64, struct{}]
+	// GameOverRoute shows the stats of a finished game.
+	GameOverRoute Route[GameOverParams, struct{}]
+	// HighScoresRoute shows the high score table for games with the given
+	// rules.
+	HighScoresRoute Route[Rules, struct{}]
+	// CreditsRoute shows the credits.
+	CreditsRoute Route[struct{}, struct{}]
+	// ReplaysRoute lists saved replays and plays the selected one.
+	ReplaysRoute Route[struct{}, struct{}]
+	// ReplayRoute plays back a recorded game.
+	ReplayRoute Route[*Replay, struct{}]
+	// ControlsRoute lets the player change the keys bound to each action,
+	// laid out on the given grid.
+	ControlsRoute Route[*Grid, struct{}]
 
-	game := NewGameState(grid.Rows, grid.Cols)
+	pauseRoute     Route[*Grid, pauseChoice]
+	enterNameRoute Route[struct{}, string]
+)
 
-	// The starting speed of falling blocks. bigger == easier.
-	startingTicksPerStep := 120.0
-	fallingBlocks := NewFallingBlocks(startingTicksPerStep)
+func init() {
+	MenuRoute = NewRoute("menu", newMenuScene)
+	GameRoute = NewRoute("game", newGameScene)
+	ContinueRoute = NewRoute("continue", newContinueScene)
+	GameOverRoute = NewRoute("game-over", newGameOverScene)
+	HighScoresRoute = NewRoute("high-scores", newHighScoresScene)
+	CreditsRoute = NewRoute("credits", newCreditsScene)
+	ReplaysRoute = NewRoute("replays", newReplaysScene)
+	ReplayRoute = NewRoute("replay", newReplayScene)
+	ControlsRoute = NewRoute("controls", newControlsScene)
+	pauseRoute = NewRoute("pause", newPauseScene)
+	enterNameRoute = NewRoute("enter-name", newEnterNameScene)
+}
 
-	var ticks float64
-	var score float64
-	nextSpeedup := startingTicksPerStep + 10000
-	scoreRenderer := NewScoreRenderer(
-		grid.ColumnToCell(grid.Cols-2),
-		grid.RowToCell(0),
-	)
+// The options of the menus.
+const (
+	optContinue   = "Continue"
+	optNewGame    = "New Game"
+	optReplays    = "Replays"
+	optHighScores = "High Scores"
+	optCredits    = "Credits"
+	optControls   = "Controls"
+	optExit       = "Exit"
+	optResume     = "Resume"
+	optRestart    = "Restart"
+	optQuit       = "Quit to Menu"
+	optRetry      = "Retry"
+	optMenu       = "Main Menu"
+)
 
-	var slamTrailRenderTicks int
-	var slamimgbuf *ImageBuffer
+// MenuGrid returns the grid that menus are laid out on, the board of a classic
+// game. It does not depend on the rules being played, so that menus fit their
+// window whatever the size of the board.
+func MenuGrid() *Grid {
+	rules := DefaultRules()
+	return &Grid{Rows: rules.Rows, Cols: rules.Cols, SquareSize: 50}
+}
 
-	for !win.Closed() {
-		ticks++
+// baseScene remembers the router showing a scene, and does nothing else when
+// the scene enters or exits. Scenes embed it and override what they need.
+type baseScene struct {
+	router *Router
+}
 
-		imgbuf := NewImageBuffer()
+// OnEnter implements the Scene interface.
+func (s *baseScene) OnEnter(r *Router) {
+	s.router = r
+}
 
-		if win.JustPressed(pixelgl.KeyQ) ||
-			win.JustPressed(pixelgl.KeyEscape) {
-			done <- GoToMenu
-			return
-		}
+// OnExit implements the Scene interface.
+func (s *baseScene) OnExit() {}
+
+// GameParams are the parameters of a game.
+type GameParams struct {
+	Rules Rules
+	// Seed determines the blocks that fall during the game.
+	Seed int64
+	// NewSeed returns the seed of each new game started after this one.
+	NewSeed func() int64
+}
+
+// next returns the parameters of a new game started after this one.
+func (params GameParams) next() GameParams {
+	params.Seed = params.NewSeed()
+	return params
+}
+
+// gameScene runs the game in an engine. Games started after it have the same
+// rules, and new seeds.
+//
+// If the player quits, the game is saved so that it can be continued later.
+// If the game ends or is restarted, any saved game is deleted. A replay of the
+// game is saved when it ends, and the game is replaced with its stats.
+//
+// The window is fit to the game's board while it runs, since a saved game may
+// have been played with rules of a different size than the menus.
+type gameScene struct {
+	baseScene
+	exit    Exit[struct{}]
+	engine  *Engine
+	params  GameParams
+	grid    *Grid
+	effects *Effects
+
+	// The runner advances the game by the time on clock, which only passes
+	// while the game is on top of the stack.
+	clock    *ManualClock
+	runner   *Runner
+	controls *Controls
+	bgMusic  int
+}
+
+// newGameScene returns a scene that runs a new game.
+func newGameScene(params GameParams, exit Exit[struct{}]) Scene {
+	return newEngineScene(NewEngine(params.Rules, params.Seed), params.NewSeed, exit)
+}
+
+// newContinueScene returns a scene that resumes the game that was saved when
+// the player last quit. newSeed returns the seed of each new game started
+// after the saved one.
+//
+// If the save cannot be restored, because it is corrupt or from an older
+// version, it is discarded and the player is told why there is no game to
+// continue.
+func newContinueScene(newSeed func() int64, exit Exit[struct{}]) Scene {
+	save, err := LoadSaveGame()
+	if err != nil {
+		log.Println("failed to load saved game:", err)
+		return discardSaveGame(exit)
+	}
+	engine, err := RestoreEngine(save)
+	if err != nil {
+		log.Println("failed to restore saved game:", err)
+		return discardSaveGame(exit)
+	}
+	return newEngineScene(engine, newSeed, exit)
+}
 
-		if win.JustPressed(pixelgl.KeyS) {
-			slamimgbuf = NewImageBuffer()
-			PlaySound(SlamSound)
-			blocksStart := fallingBlocks.Blocks()
-			fallingBlocks.Slam(game)
-			blocksEnd := fallingBlocks.Blocks()
-			for i := range blocksStart {
-				col := blocksStart[i].Col
-				rowStart := blocksStart[i].Row
-				rowEnd := blocksEnd[i].Row
-				drawSlamTrail(col, rowStart, rowEnd, grid, slamimgbuf)
-				slamTrailRenderTicks = 30
+// discardSaveGame discards a save that cannot be continued, and returns a
+// scene that tells the player.
+func discardSaveGame(exit Exit[struct{}]) Scene {
+	if err := DiscardSaveGame(); err != nil {
+		log.Println("failed to discard saved game:", err)
+	}
+	return &messageScene{exit: exit, lines: []string{
+		"The saved game could not be loaded.",
+		"It may be from an older version.",
+		"",
+		"It has been discarded.",
+	}}
+}
+
+// messageScene shows a message until the player selects or backs out.
+type messageScene struct {
+	baseScene
+	exit     Exit[struct{}]
+	lines    []string
+	controls *Controls
+}
+
+// OnEnter implements the Scene interface.
+func (s *messageScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *messageScene) Update(time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionMenuSelect) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(struct{}{})
+	}
+}
+
+// Draw implements the Scene interface.
+func (s *messageScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	for i, line := range s.lines {
+		target.Text(grid.ColumnToPixel(0)+10, grid.PixelHeight()-20-float64(i)*16, line, ColorText)
+	}
+}
+
+// newEngineScene returns a scene that runs the game in the given engine.
+// Games started after it have seeds returned by newSeed.
+func newEngineScene(engine *Engine, newSeed func() int64, exit Exit[struct{}]) *gameScene {
+	clock := &ManualClock{}
+	return &gameScene{
+		exit:    exit,
+		engine:  engine,
+		params:  GameParams{Rules: engine.Rules(), Seed: engine.Seed(), NewSeed: newSeed},
+		effects: &Effects{},
+		clock:   clock,
+		runner:  NewRunner(engine, clock),
+	}
+}
+
+// OnEnter implements the Scene interface.
+func (s *gameScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.grid = &Grid{
+		Rows:       s.engine.Game().RowCount(),
+		Cols:       s.engine.Game().ColCount(),
+		SquareSize: r.Grid().SquareSize,
+	}
+	r.fitWindow(s.grid)
+	s.loadControls()
+	LoadSounds()
+	s.bgMusic = LoopSound(BackgroundMusic)
+}
+
+// OnExit implements the Scene interface.
+func (s *gameScene) OnExit() {
+	StopSound(s.bgMusic)
+	s.router.fitWindow(s.router.Grid())
+}
+
+// loadControls loads the player's bindings, which may have been changed from
+// the pause menu.
+func (s *gameScene) loadControls() {
+	bindings := LoadBindings()
+	s.runner.SetAutoShift(bindings.AutoShift)
+	s.controls = NewControls(s.router.Window(), bindings)
+}
+
+// Update implements the Scene interface.
+func (s *gameScene) Update(dt time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionPause) ||
+		s.router.Window().JustPressed(pixelgl.Button(reservedKey)) {
+		DuckSound(s.bgMusic, true)
+		Push(s.router, pauseRoute, s.grid, s.resume)
+		return
+	}
+	if s.controls.JustPressed(ActionQuit) {
+		s.quit()
+		return
+	}
+
+	if s.controls.JustPressed(ActionSlam) {
+		s.runner.Input(SlamInput)
+	}
+	if s.controls.JustPressed(ActionShiftLeft) {
+		s.runner.Input(ShiftLeftInput)
+	}
+	if s.controls.JustPressed(ActionShiftRight) {
+		s.runner.Input(ShiftRightInput)
+	}
+	s.runner.Hold(s.controls.HeldShift())
+	if s.controls.JustPressed(ActionHold) {
+		s.runner.Input(HoldInput)
+	}
+
+	s.clock.Advance(dt)
+	for _, step := range s.runner.Update() {
+		s.effects.Update(step)
+		playStepSounds(step)
+
+		if step.Over {
+			saveReplay(s.engine)
+			if err := DeleteSaveGame(); err != nil {
+				log.Println("failed to delete saved game:", err)
 			}
+			Replace(s.exit, GameOverRoute, GameOverParams{Stats: s.engine.Stats(), Game: s.params})
+			return
 		}
+	}
+}
 
-		if win.JustPressed(pixelgl.KeyA) {
-			PlaySound(ShiftSound)
-			fallingBlocks.ShiftLeft(game)
-		}
-		if win.JustPressed(pixelgl.KeyD) {
-			PlaySound(ShiftSound)
-			fallingBlocks.ShiftRight(game)
-		}
+// resume handles the option the player chose from the pause menu.
+func (s *gameScene) resume(choice pauseChoice) {
+	DuckSound(s.bgMusic, false)
+	s.loadControls()
 
-		// Update sub systems.
-		fallingBlocks.Update(ticks, game)
-		if nextSpeedup <= ticks {
-			fallingBlocks.Speedup()
-			nextSpeedup = ticks + 10000
+	switch choice {
+	case pauseRestart:
+		if err := DeleteSaveGame(); err != nil {
+			log.Println("failed to delete saved game:", err)
 		}
+		Replace(s.exit, GameRoute, s.params.next())
+	case pauseQuit:
+		s.quit()
+	default:
+		s.runner.Pause()
+	}
+}
 
-		// Add landed blocks to the grid.
-		var didBlockDie, didBlockMerge bool
-		for _, block := range fallingBlocks.Blocks() {
-			landingType, lrow, lcol := fallingBlocks.DescribeLanding(block, game)
-			switch landingType {
-			case Unlanded:
-				continue
-			case LandedOnLiveBlock:
-				didBlockMerge = true
-			}
+// quit saves the game and returns to the scene that started it.
+func (s *gameScene) quit() {
+	if err := StoreSaveGame(s.engine.Save()); err != nil {
+		log.Println("failed to save game:", err)
+	}
+	s.exit.Pop(struct{}{})
+}
 
-			score++
-			newBlock := Block{Row: lrow, Col: lcol, Value: block.Value}
-			if err := game.AddBlock(newBlock); err != nil {
-				log.Fatal(err)
-			}
-			if game.IsDead(newBlock.Row, newBlock.Col) {
-				didBlockDie = true
-			}
-			fallingBlocks.Remove(block.Row, block.Col)
+// Draw implements the Scene interface.
+func (s *gameScene) Draw(target Canvas) {
+	DrawGame(target, s.grid, s.engine, s.effects)
+	DrawSidePanel(target, s.grid, s.engine)
+}
+
+// pauseChoice is the option chosen from the pause menu.
+type pauseChoice int
+
+const (
+	pauseResume pauseChoice = iota
+	pauseRestart
+	pauseQuit
+)
+
+// pauseScene shows the pause menu over the game until the player chooses an
+// option. The game is not updated while paused.
+//
+// The menu is laid out on the game's grid, since the window is fit to the
+// game's board rather than to the menus.
+type pauseScene struct {
+	baseScene
+	exit      Exit[pauseChoice]
+	grid      *Grid
+	options   []string
+	selection int
+	controls  *Controls
+}
+
+func newPauseScene(grid *Grid, exit Exit[pauseChoice]) Scene {
+	return &pauseScene{
+		exit: exit,
+		grid: grid,
+		options: []string{
+			optResume,
+			optRestart,
+			optControls,
+			optQuit,
+		},
+	}
+}
+
+// overlay marks the pause menu as an overlay, so that the router draws the
+// game beneath it first.
+func (s *pauseScene) overlay() {}
+
+// OnEnter implements the Scene interface.
+func (s *pauseScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *pauseScene) Update(time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionPause) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(pauseResume)
+		return
+	}
+	s.selection = s.controls.UpdateSelection(s.selection, len(s.options))
+	if s.controls.JustPressed(ActionMenuSelect) {
+		switch s.options[s.selection] {
+		case optResume:
+			s.exit.Pop(pauseResume)
+		case optRestart:
+			s.exit.Pop(pauseRestart)
+		case optControls:
+			Push(s.router, ControlsRoute, s.grid, func(struct{}) {
+				s.controls = NewControls(s.router.Window(), LoadBindings())
+			})
+		case optQuit:
+			s.exit.Pop(pauseQuit)
 		}
+	}
+}
+
+// Draw implements the Scene interface.
+func (s *pauseScene) Draw(target Canvas) {
+	bounds := s.router.Window().Bounds()
+	target.Rect(0, 0, bounds.W(), bounds.H(), ColorPauseOverlay)
+	drawOptions(s.options, s.selection, 1, s.grid, target)
+}
+
+// GameOverParams are the parameters of the game over scene.
+type GameOverParams struct {
+	// Stats are the stats of the finished game.
+	Stats Stats
+	// Game are the parameters the finished game was started with.
+	Game GameParams
+}
+
+// gameOverScene shows the stats of a finished game.
+//
+// If the game's score qualifies for the high score table, the player is first
+// asked to enter their name. The player can then retry the game with the same
+// seed, start a new game with a new seed or return to the main menu.
+type gameOverScene struct {
+	baseScene
+	exit      Exit[struct{}]
+	params    GameOverParams
+	lines     []string
+	options   []string
+	selection int
+	controls  *Controls
+}
+
+func newGameOverScene(params GameOverParams, exit Exit[struct{}]) Scene {
+	stats := params.Stats
+	return &gameOverScene{
+		exit:   exit,
+		params: params,
+		lines: []string{
+			"GAME OVER!",
+			"",
+			fmt.Sprintf("Score: %d", stats.Score),
+			fmt.Sprintf("Blocks landed: %d", stats.BlocksLanded),
+			fmt.Sprintf("Merges: %d  Zeroes: %d", stats.Merges, stats.Zeroes),
+			fmt.Sprintf("Deaths: %d  Max chain: %d", stats.Deaths, stats.MaxChain),
+			fmt.Sprintf("Points: %d land, %d merge, %d zero", stats.Points.Land, stats.Points.Merge, stats.Points.Zero),
+			fmt.Sprintf("        %d chain, %d death", stats.Points.Chain, stats.Points.Death),
+			fmt.Sprintf("Longest survival: %v", stats.LongestSurvival.Round(time.Second/10)),
+			fmt.Sprintf("Level: %d  Peak speed: %.2fx", stats.Level, stats.PeakSpeed),
+			fmt.Sprintf("Seed: %d", stats.Seed),
+		},
+		options: []string{
+			optRetry,
+			optNewGame,
+			optMenu,
+		},
+	}
+}
+
+// OnEnter implements the Scene interface.
+func (s *gameOverScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+
+	stats := s.params.Stats
+	key := HighScoreKey(stats.Rows, stats.Cols, stats.Mode)
+	if stats.Score > 0 && LoadHighScores().Qualifies(key, stats.Score) {
+		Push(r, enterNameRoute, struct{}{}, s.recordHighScore)
+	}
+}
 
-		if didBlockMerge && didBlockDie {
-			PlaySound(DieSound)
-		} else if didBlockMerge {
-			PlaySound(MergeSound)
+// recordHighScore adds the game to the high score table under the name the
+// player entered, unless they skipped entering one.
+func (s *gameOverScene) recordHighScore(name string) {
+	if name == "" {
+		return
+	}
+	stats := s.params.Stats
+	scores := LoadHighScores()
+	rank := scores.Add(HighScoreKey(stats.Rows, stats.Cols, stats.Mode), HighScore{
+		Name:     name,
+		Score:    stats.Score,
+		Date:     time.Now(),
+		Seed:     stats.Seed,
+		Duration: stats.Duration,
+	})
+	if err := StoreHighScores(scores); err != nil {
+		log.Println("failed to save high scores:", err)
+	}
+	if rank >= 0 {
+		s.lines[1] = fmt.Sprintf("New high score! #%d", rank+1)
+	}
+}
+
+// Update implements the Scene interface.
+func (s *gameOverScene) Update(time.Duration) {
+	s.controls.Update()
+	s.selection = s.controls.UpdateSelection(s.selection, len(s.options))
+	if s.controls.JustPressed(ActionMenuSelect) {
+		switch s.options[s.selection] {
+		case optRetry:
+			Replace(s.exit, GameRoute, s.params.Game)
+		case optNewGame:
+			Replace(s.exit, GameRoute, s.params.Game.next())
+		case optMenu:
+			s.exit.Pop(struct{}{})
 		}
+	}
+}
 
-		// If all blocks have landed, generate a new wave of blocks.
-		if fallingBlocks.Length() == 0 {
-			fallingBlocks.Random(game.ColCount())
+// Draw implements the Scene interface.
+func (s *gameOverScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	for i, line := range s.lines {
+		target.Text(grid.ColumnToPixel(0)+10, grid.PixelHeight()-20-float64(i)*16, line, ColorText)
+	}
+	drawOptions(s.options, s.selection, grid.Rows-len(s.options), grid, target)
+}
+
+// enterNameScene asks the player to type their name for the high score
+// table.
+//
+// It pops with the name, or an empty name if the player backed out to skip.
+// Players without a keyboard can accept the default name.
+type enterNameScene struct {
+	baseScene
+	exit     Exit[string]
+	name     []rune
+	controls *Controls
+}
+
+func newEnterNameScene(_ struct{}, exit Exit[string]) Scene {
+	return &enterNameScene{exit: exit}
+}
+
+// OnEnter implements the Scene interface.
+func (s *enterNameScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *enterNameScene) Update(time.Duration) {
+	const maxNameLength = 12
+	const defaultName = "Player"
+
+	win := s.router.Window()
+	s.controls.Update()
+	if s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop("")
+		return
+	}
+	// Space is typed into the name rather than accepting it.
+	if s.controls.JustPressed(ActionMenuSelect) && !win.JustPressed(pixelgl.KeySpace) {
+		if len(s.name) == 0 {
+			s.exit.Pop(defaultName)
+		} else {
+			s.exit.Pop(string(s.name))
+		}
+		return
+	}
+	if win.JustPressed(pixelgl.KeyBackspace) && len(s.name) > 0 {
+		s.name = s.name[:len(s.name)-1]
+	}
+	for _, c := range win.Typed() {
+		if len(s.name) < maxNameLength && c >= ' ' && c <= '~' {
+			s.name = append(s.name, c)
 		}
+	}
+}
+
+// Draw implements the Scene interface.
+func (s *enterNameScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	target.Text(grid.ColumnToPixel(0)+10, grid.RowToCell(1), "New high score!", ColorText)
+	target.Text(grid.ColumnToPixel(0)+10, grid.RowToCell(2), "Enter your name:", ColorText)
+	target.Text(grid.ColumnToPixel(0)+10, grid.RowToCell(3), string(s.name)+"_", ColorText)
+}
+
+// highScoresScene shows the high score table for games with the given rules.
+type highScoresScene struct {
+	baseScene
+	exit     Exit[struct{}]
+	lines    []string
+	controls *Controls
+}
+
+func newHighScoresScene(rules Rules, exit Exit[struct{}]) Scene {
+	key := HighScoreKey(rules.Rows, rules.Cols, rules.Mode())
+	scores := LoadHighScores().Top(key)
+
+	lines := []string{
+		"HIGH SCORES",
+		fmt.Sprintf("%dx%d, %s", rules.Cols, rules.Rows, rules.Mode()),
+		"",
+	}
+	if len(scores) == 0 {
+		lines = append(lines, "No high scores yet")
+	}
+	for i, score := range scores {
+		lines = append(lines, fmt.Sprintf("%2d. %-12s %6d", i+1, score.Name, score.Score))
+	}
+	return &highScoresScene{exit: exit, lines: lines}
+}
+
+// OnEnter implements the Scene interface.
+func (s *highScoresScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *highScoresScene) Update(time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionQuit) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(struct{}{})
+	}
+}
+
+// Draw implements the Scene interface.
+func (s *highScoresScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	for i, line := range s.lines {
+		target.Text(grid.ColumnToPixel(0)+10, grid.PixelHeight()-20-float64(i)*16, line, ColorText)
+	}
+}
+
+// creditsLineHeight is the height of each line of the credits.
+const creditsLineHeight = 16
+
+// credits is the text of the credits. It is built into the binary, so that
+// the credits show wherever the game is run from.
+//
+//go:embed assets/credits.txt
+var credits string
+
+// creditsScene shows the credits.
+//
+// Moving up and down through the menu scrolls the credits.
+type creditsScene struct {
+	baseScene
+	exit     Exit[struct{}]
+	lines    []string
+	controls *Controls
+	// The index of the line at the top of the window.
+	top int
+}
+
+func newCreditsScene(_ struct{}, exit Exit[struct{}]) Scene {
+	lines := strings.Split(strings.TrimRight(credits, "\n"), "\n")
+	return &creditsScene{exit: exit, lines: lines}
+}
+
+// visible returns the number of lines of the credits shown at once.
+func (s *creditsScene) visible() int {
+	return int(s.router.Grid().PixelHeight()/creditsLineHeight) - 2
+}
+
+// OnEnter implements the Scene interface.
+func (s *creditsScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *creditsScene) Update(time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionQuit) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(struct{}{})
+		return
+	}
+	if s.controls.JustPressed(ActionMenuDown) && s.top < len(s.lines)-s.visible() {
+		s.top++
+	}
+	if s.controls.JustPressed(ActionMenuUp) && s.top > 0 {
+		s.top--
+	}
+}
 
-		if game.IsOver() {
-			println("GAME OVER!")
+// Draw implements the Scene interface.
+func (s *creditsScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	for i := s.top; i < len(s.lines) && i < s.top+s.visible(); i++ {
+		target.Text(grid.ColumnToPixel(0)+10,
+			grid.PixelHeight()-20-float64(i-s.top)*creditsLineHeight, s.lines[i], ColorText)
+	}
+}
+
+// replaysScene lists saved replays and plays the selected one.
+type replaysScene struct {
+	baseScene
+	exit      Exit[struct{}]
+	paths     []string
+	selection int
+	controls  *Controls
+}
+
+func newReplaysScene(_ struct{}, exit Exit[struct{}]) Scene {
+	paths, err := ListReplays()
+	if err != nil {
+		log.Println(err)
+	}
+	return &replaysScene{exit: exit, paths: paths}
+}
+
+// OnEnter implements the Scene interface.
+func (s *replaysScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), LoadBindings())
+}
+
+// Update implements the Scene interface.
+func (s *replaysScene) Update(time.Duration) {
+	s.controls.Update()
+	if s.controls.JustPressed(ActionQuit) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(struct{}{})
+		return
+	}
+	if len(s.paths) == 0 {
+		return
+	}
+	s.selection = s.controls.UpdateSelection(s.selection, len(s.paths))
+	if s.controls.JustPressed(ActionMenuSelect) {
+		replay, err := LoadReplay(s.paths[s.selection])
+		if err != nil {
+			log.Println(err)
 			return
 		}
+		Push(s.router, ReplayRoute, replay, func(struct{}) {
+			s.controls = NewControls(s.router.Window(), LoadBindings())
+		})
+	}
+}
 
-		// Render.
-		win.Clear(ColorBg)
-		drawGrid(game, grid, imgbuf)
-		for _, block := range fallingBlocks.Blocks() {
-			drawBlock(block, grid, ColorFallingBlock, imgbuf)
+// Draw implements the Scene interface.
+func (s *replaysScene) Draw(target Canvas) {
+	grid := s.router.Grid()
+	if len(s.paths) == 0 {
+		target.Text(grid.ColumnToPixel(1), grid.RowToCell(1), "No replays", ColorText)
+	}
+	// The number of replays shown at once.
+	pageSize := grid.Rows - 2
+	first := s.selection - s.selection%pageSize
+	for i := first; i < len(s.paths) && i < first+pageSize; i++ {
+		row := i - first + 1
+		if i == s.selection {
+			target.Rect(grid.ColumnToPixel(1), grid.RowToPixel(row),
+				grid.PixelWidth()-2*grid.SquareSize, grid.SquareSize, ColorMenuOption)
 		}
-		if slamTrailRenderTicks > 0 {
-			slamTrailRenderTicks--
-			slamimgbuf.Renderer().Render(win)
+		target.Text(grid.ColumnToPixel(1), grid.RowToCell(row), ReplayName(s.paths[i]), ColorText)
+	}
+}
+
+// replayScene plays back a recorded game.
+//
+// The replay can be played at 1x, 2x or 4x speed using the 1, 2 and 4 keys.
+// Selecting pauses the replay, and the period key steps a single tick while
+// paused. The window is fit to the replay's board while it plays.
+type replayScene struct {
+	baseScene
+	exit     Exit[struct{}]
+	controls *Controls
+	replay   *Replay
+	player   *ReplayPlayer
+	grid     *Grid
+	effects  *Effects
+
+	speed    int
+	paused   bool
+	clock    *ManualClock
+	timestep *Timestep
+}
+
+func newReplayScene(replay *Replay, exit Exit[struct{}]) Scene {
+	clock := &ManualClock{}
+	return &replayScene{
+		exit:     exit,
+		replay:   replay,
+		player:   NewReplayPlayer(replay),
+		effects:  &Effects{},
+		speed:    1,
+		clock:    clock,
+		timestep: NewTimestep(clock),
+	}
+}
+
+// OnEnter implements the Scene interface.
+func (s *replayScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.grid = &Grid{
+		Rows:       s.replay.Rules.Rows,
+		Cols:       s.replay.Rules.Cols,
+		SquareSize: r.Grid().SquareSize,
+	}
+	r.fitWindow(s.grid)
+	s.controls = NewControls(r.Window(), LoadBindings())
+	LoadSounds()
+}
+
+// OnExit implements the Scene interface.
+func (s *replayScene) OnExit() {
+	s.router.fitWindow(s.router.Grid())
+}
+
+// Update implements the Scene interface.
+func (s *replayScene) Update(dt time.Duration) {
+	win := s.router.Window()
+	s.controls.Update()
+	if s.controls.JustPressed(ActionQuit) ||
+		s.controls.JustPressed(ActionMenuBack) {
+		s.exit.Pop(struct{}{})
+		return
+	}
+	switch {
+	case win.JustPressed(pixelgl.Key1):
+		s.speed = 1
+	case win.JustPressed(pixelgl.Key2):
+		s.speed = 2
+	case win.JustPressed(pixelgl.Key4):
+		s.speed = 4
+	case s.controls.JustPressed(ActionMenuSelect):
+		s.paused = !s.paused
+	}
+
+	s.clock.Advance(dt)
+	steps := s.timestep.Update() * s.speed
+	if s.paused {
+		steps = 0
+		if win.JustPressed(pixelgl.KeyPeriod) {
+			steps = 1
 		}
-		imgbuf.Renderer().Render(win)
-		scoreRenderer.SetScore(score)
-		scoreRenderer.Render(win)
-		win.Update()
+	}
+	for i := 0; i < steps && !s.player.Done(); i++ {
+		result := s.player.Step()
+		s.effects.Update(result)
+		playStepSounds(result)
 	}
 }
 
-// ViewMenu runs the main menu.
-func ViewMenu(win *pixelgl.Window, grid *Grid, done chan GoToCmd) {
-	const optNewGame = "New Game"
-	const optCredits = "Credits"
-	const optControls = "Controls"
-	const optExit = "Exit"
+// Draw implements the Scene interface.
+func (s *replayScene) Draw(target Canvas) {
+	DrawGame(target, s.grid, s.player.Engine(), s.effects)
+	DrawSidePanel(target, s.grid, s.player.Engine())
+	status := fmt.Sprintf("%dx", s.speed)
+	switch {
+	case s.player.Done():
+		status = "END"
+	case s.paused:
+		status = "PAUSED"
+	}
+	target.Text(s.grid.ColumnToPixel(0), s.grid.RowToCell(0), status, ColorText)
+}
+
+// MenuParams are the parameters of the main menu.
+type MenuParams struct {
+	// Rules are the rules of new games, and of the high scores shown.
+	Rules Rules
+	// NewSeed returns the seed of each new game.
+	NewSeed func() int64
+}
 
-	options := []string{
+// menuScene runs the main menu. It pops when the player chooses to exit.
+type menuScene struct {
+	baseScene
+	exit      Exit[struct{}]
+	params    MenuParams
+	options   []string
+	selection int
+	controls  *Controls
+}
+
+func newMenuScene(params MenuParams, exit Exit[struct{}]) Scene {
+	return &menuScene{exit: exit, params: params}
+}
+
+// OnEnter implements the Scene interface.
+func (s *menuScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.reset()
+}
+
+// reset shows the menu from the top. It is called whenever the menu is shown
+// again, since there may now be a game to continue, and the bindings may have
+// changed.
+func (s *menuScene) reset() {
+	s.options = []string{
 		optNewGame,
+		optReplays,
+		optHighScores,
 		optControls,
 		optCredits,
 		optExit,
 	}
+	if HasSaveGame() {
+		s.options = append([]string{optContinue}, s.options...)
+	}
+	s.selection = 0
+	s.controls = NewControls(s.router.Window(), LoadBindings())
+}
 
-	selection := 0
-	imgbuf := NewImageBuffer()
+// Update implements the Scene interface.
+func (s *menuScene) Update(time.Duration) {
+	s.controls.Update()
+	s.selection = s.controls.UpdateSelection(s.selection, len(s.options))
+	if !s.controls.JustPressed(ActionMenuSelect) {
+		return
+	}
 
-	for !win.Closed() {
-		if win.JustPressed(pixelgl.KeyDown) ||
-			win.JustPressed(pixelgl.KeyRight) ||
-			win.JustPressed(pixelgl.KeyTab) {
-			selection = (selection + 1) % len(options)
-		}
-		if win.JustPressed(pixelgl.KeyUp) ||
-			win.JustPressed(pixelgl.KeyLeft) {
-			selection--
-			if selection < 0 {
-				selection = len(options) - 1
-			}
-		}
-		if win.JustPressed(pixelgl.KeyEnter) ||
-			win.JustPressed(pixelgl.KeySpace) {
-			switch options[selection] {
-			case optNewGame:
-				done <- GoToNewGame
-				return
-			case optExit:
-				done <- GoToExit
-				return
-			case optControls:
-				done <- GoToControls
-				return
-			}
-		}
+	reset := func(struct{}) { s.reset() }
+	switch s.options[s.selection] {
+	case optContinue:
+		Push(s.router, ContinueRoute, s.params.NewSeed, reset)
+	case optNewGame:
+		Push(s.router, GameRoute, GameParams{
+			Rules:   s.params.Rules,
+			Seed:    s.params.NewSeed(),
+			NewSeed: s.params.NewSeed,
+		}, reset)
+	case optExit:
+		s.exit.Pop(struct{}{})
+	case optControls:
+		Push(s.router, ControlsRoute, s.router.Grid(), reset)
+	case optReplays:
+		Push(s.router, ReplaysRoute, struct{}{}, reset)
+	case optHighScores:
+		Push(s.router, HighScoresRoute, s.params.Rules, reset)
+	case optCredits:
+		Push(s.router, CreditsRoute, struct{}{}, reset)
+	}
+}
 
-		for i, option := range options {
-			var color color.RGBA
-			if selection == i {
-				color = ColorMenuOption
-			} else {
-				color = ColorBg
-			}
-			drawRect(imgbuf, grid.RowToPixel(i+1),
-				grid.ColumnToPixel(1), 100, 50, color)
-			imgbuf.Text(grid.RowToCell(i+1), grid.ColumnToCell(1),
-				option)
-		}
+// Draw implements the Scene interface.
+func (s *menuScene) Draw(target Canvas) {
+	drawOptions(s.options, s.selection, 1, s.router.Grid(), target)
+}
 
-		win.Clear(ColorBg)
-		imgbuf.Renderer().Render(win)
-		win.Update()
+// moveSelection returns the menu selection after moving it down and/or up by
+// one option. The selection wraps around the given number of options.
+func moveSelection(selection int, count int, down bool, up bool) int {
+	if down {
+		selection = (selection + 1) % count
+	}
+	if up {
+		selection--
+		if selection < 0 {
+			selection = count - 1
+		}
 	}
+	return selection
 }
 
-// ViewControls displays user controls.
-func ViewControls(win *pixelgl.Window, grid *Grid, done chan GoToCmd) {
-	controls := []struct{ Key, Desc string }{
-		{"a", "shift left"},
-		{"d", "shift right"},
-		{"s", "slam blocks to bottom of screen"},
-		{"q, Esc", "exit to main menu"},
+// drawOptions draws a list of menu options, one per row starting at firstRow,
+// highlighting the selected option.
+func drawOptions(options []string, selection int, firstRow int, grid *Grid, canvas Canvas) {
+	for i, option := range options {
+		var color color.RGBA
+		if selection == i {
+			color = ColorMenuOption
+		} else {
+			color = ColorBg
+		}
+		canvas.Rect(grid.ColumnToPixel(1), grid.RowToPixel(firstRow+i),
+			100, 50, color)
+		canvas.Text(grid.ColumnToCell(1), grid.RowToCell(firstRow+i),
+			option, ColorText)
 	}
+}
 
-	for !win.Closed() {
-		if win.JustPressed(pixelgl.KeyQ) ||
-			win.JustPressed(pixelgl.KeyEscape) {
-			done <- GoToMenu
-			return
-		}
+// controlsLineHeight is the height of each line of the controls scene.
+const controlsLineHeight = 16
 
-		imgbuf := NewImageBuffer()
+// controlsScene shows the keys bound to each action until the player presses
+// q or backs out. Selecting an action binds it to the next key pressed, and r
+// resets the default bindings. Changes are saved as soon as they are made.
+type controlsScene struct {
+	baseScene
+	exit      Exit[struct{}]
+	grid      *Grid
+	bindings  *Bindings
+	controls  *Controls
+	selection int
+	// binding is true while waiting for the key to bind the selected action
+	// to, and message explains the last change or why it failed.
+	binding bool
+	message string
+}
 
-		for i, ctrl := range controls {
-			imgbuf.Text(
-				grid.RowToCell(i+1),
-				grid.ColumnToPixel(1),
-				ctrl.Key+": "+ctrl.Desc)
-		}
+func newControlsScene(grid *Grid, exit Exit[struct{}]) Scene {
+	return &controlsScene{exit: exit, grid: grid, bindings: LoadBindings()}
+}
 
-		win.Clear(ColorBg)
-		imgbuf.Renderer().Render(win)
-		win.Update()
-	}
+// OnEnter implements the Scene interface.
+func (s *controlsScene) OnEnter(r *Router) {
+	s.baseScene.OnEnter(r)
+	s.controls = NewControls(r.Window(), s.bindings)
 }
 
-func drawBlock(block Block, grid *Grid, color color.RGBA, buf *ImageBuffer) {
-	col := grid.ColumnToPixel(block.Col)
-	row := grid.RowToPixel(block.Row)
-	drawSquare(row, col, grid.SquareSize, color, buf)
-	col = grid.ColumnToCell(block.Col)
-	row = grid.RowToCell(block.Row)
-	buf.Text(row, col, strconv.Itoa(block.Value))
+// store saves the bindings.
+func (s *controlsScene) store() {
+	if err := StoreBindings(s.bindings); err != nil {
+		log.Println("failed to save bindings:", err)
+		s.message = "Failed to save controls"
+	}
 }
 
-func drawGrid(game *GameState, grid *Grid, buf *ImageBuffer) {
-	for row := 0; row < game.RowCount(); row++ {
-		for col := 0; col < game.ColCount(); col++ {
-			if !game.IsEmpty(row, col) {
-				color := ColorLiveBlock
-				if game.IsDead(row, col) {
-					color = ColorDeadBlock
-				}
+// Update implements the Scene interface.
+func (s *controlsScene) Update(time.Duration) {
+	win := s.router.Window()
+	s.controls.Update()
+	action := Actions[s.selection]
+	switch {
+	case s.binding && s.controls.JustPressed(ActionMenuBack):
+		s.binding = false
+		s.message = ""
+	case s.binding:
+		key, ok := justPressedKey(win)
+		if !ok {
+			break
+		}
+		if err := s.bindings.Bind(action, key); err != nil {
+			s.message = err.Error()
+			break
+		}
+		s.binding = false
+		s.message = fmt.Sprintf("%s: %v", action.Desc(), key)
+		s.store()
+	case s.controls.JustPressed(ActionQuit) || s.controls.JustPressed(ActionMenuBack):
+		s.exit.Pop(struct{}{})
+	case s.controls.JustPressed(ActionMenuSelect):
+		s.binding = true
+		s.message = fmt.Sprintf("Press a key for %s, Esc to cancel", action.Desc())
+	case win.JustPressed(pixelgl.KeyR):
+		s.bindings = DefaultBindings()
+		s.message = "Controls reset"
+		s.store()
+	default:
+		s.selection = s.controls.UpdateSelection(s.selection, len(Actions))
+	}
+}
 
-				block := Block{
-					Col:   col,
-					Row:   row,
-					Value: game.ValueAt(row, col),
-				}
-				drawBlock(block, grid, color, buf)
-			}
+// Draw implements the Scene interface.
+func (s *controlsScene) Draw(target Canvas) {
+	lines := []string{"CONTROLS", ""}
+	for _, action := range Actions {
+		line := s.bindings.KeyNames(action)
+		if buttons := s.bindings.ButtonNames(action); buttons != "" {
+			line += " / " + buttons
+		}
+		lines = append(lines, line+": "+action.Desc())
+	}
+	lines = append(lines, "", "Enter: change  r: reset  q: back", s.message)
+	for i, line := range lines {
+		y := s.grid.PixelHeight() - 20 - float64(i)*controlsLineHeight
+		if i == s.selection+2 {
+			target.Rect(0, y-4, s.router.Window().Bounds().W(), controlsLineHeight, ColorMenuOption)
 		}
+		target.Text(s.grid.ColumnToPixel(0)+10, y, line, ColorText)
 	}
 }
 
-func drawSlamTrail(col int, rowStart int, rowEnd int, grid *Grid, buf *ImageBuffer) {
-	for row := rowStart; row < rowEnd; row++ {
-		drawSquare(
-			grid.RowToPixel(row),
-			grid.ColumnToPixel(col),
-			grid.SquareSize,
-			ColorSlamTrail,
-			buf)
+// justPressedKey returns a key that was pressed since the last update of win,
+// and true, or false if no key was pressed.
+func justPressedKey(win WindowInput) (Key, bool) {
+	for b := pixelgl.KeySpace; b <= pixelgl.KeyLast; b++ {
+		if win.JustPressed(b) {
+			return Key(b), true
+		}
 	}
+	return 0, false
 }
 
-// CreateSquare returns a square at position x, y whose side length is size.
-// TODO: Finish switching to drawRect and delete this.
-func drawSquare(y float64, x float64, size float64, color color.RGBA, buf *ImageBuffer) {
-	buf.Color(color)
-	buf.Vertex(x, y)
-	buf.Vertex(x+size, y)
-	buf.Vertex(x+size, y+size)
-	buf.Vertex(x, y+size)
-	buf.Polygon()
+// playStepSounds plays the sounds for the events in the given result.
+func playStepSounds(result StepResult) {
+	if result.Slammed {
+		PlaySound(SlamSound)
+	}
+	if result.Shifted || result.Held {
+		PlaySound(ShiftSound)
+	}
+	if result.Merged && result.Died {
+		PlaySound(DieSound)
+	} else if result.Merged {
+		PlaySound(MergeSound)
+	}
+	if result.LevelUp {
+		PlaySound(LevelUpSound)
+	}
 }
 
-func drawRect(buf *ImageBuffer, y float64, x float64, w float64, h float64, color color.RGBA) {
-	buf.Color(color)
-	buf.Vertex(x, y)
-	buf.Vertex(x+w, y)
-	buf.Vertex(x+w, y+h)
-	buf.Vertex(x, y+h)
-	buf.Polygon()
+// saveReplay saves a replay of the game run by engine.
+func saveReplay(engine *Engine) {
+	if _, err := SaveReplay(engine.Replay()); err != nil {
+		log.Println("failed to save replay:", err)
+	}
 }